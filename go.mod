@@ -0,0 +1,10 @@
+module github.com/baditaflorin/llm-long-context-tests
+
+go 1.26.0
+
+require (
+	github.com/go-faker/faker/v4 v4.12.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require golang.org/x/text v0.40.0 // indirect
@@ -0,0 +1,275 @@
+// Command llm-long-context-tests generates long-context retrieval prompts with their answer key,
+// sends them to a model (-run) and grades the replies (-grade). It parses the flags into
+// promptgen.Options; package promptgen does the work.
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/baditaflorin/llm-long-context-tests/promptgen"
+)
+
+// --- Command-Line Flags ---
+// Each flag sets the promptgen.Options field of the same name.
+var opts = promptgen.DefaultOptions()
+
+func init() {
+	flag.StringVar(&opts.AnswerFormat, "answer-format", opts.AnswerFormat, "Encoding for the answer key file: json or yaml")
+	flag.StringVar(&opts.AnswerListStyle, "answer-list-style", opts.AnswerListStyle, "How name lists are written in the answer key: json (an array), comma (one comma-separated string) or newline (one name per line); lists of person records, as in the filter prompts, stay arrays so -grade and -validate-only can check their fields")
+	flag.StringVar(&opts.SystemPrompt, "system-prompt", opts.SystemPrompt, "Optional file whose contents are prepended to every generated prompt")
+	flag.Float64Var(&opts.BlankCityRate, "blank-city-rate", opts.BlankCityRate, "Fraction of entries (0-1) whose City is left unknown")
+	flag.Float64Var(&opts.FormatNoise, "format-noise", opts.FormatNoise, "Fraction of data rows (0-1) given messy formatting: an extra blank line after the row, trailing spaces or a doubled last delimiter (values are unchanged)")
+	flag.StringVar(&opts.FormatDelimiter, "format-delimiter", opts.FormatDelimiter, "Delimiter between fields in a data row (\\t is accepted for tab)")
+	flag.StringVar(&opts.FormatKVSeparator, "format-kv-separator", opts.FormatKVSeparator, "Separator between a field label and its value (\\t is accepted for tab)")
+	flag.BoolVar(&opts.CompactFields, "compact-fields", opts.CompactFields, "Strip the spaces around the field delimiter and key-value separator (Name:Ann|Age:42) to save tokens")
+	flag.StringVar(&opts.Suite, "suite", opts.Suite, "Only generate prompts from this suite (retrieval, filter, aggregation, adversarial); empty means all")
+	flag.StringVar(&opts.DataHeader, "data-header", opts.DataHeader, "Optional marker line placed before the data block (e.g. \"=== BEGIN DATA ===\")")
+	flag.StringVar(&opts.DataFooter, "data-footer", opts.DataFooter, "Optional marker line placed after the data block (e.g. \"=== END DATA ===\")")
+	flag.BoolVar(&opts.MarkerInstruction, "marker-instruction", opts.MarkerInstruction, "Append an instruction to only use the data between the header and footer markers")
+	flag.BoolVar(&opts.StrictFormat, "strict-format", opts.StrictFormat, "Append an explicit answer-format instruction (a single integer, a bulleted list of names, or 'Name: age' lines) to prompts whose answer has one of those shapes")
+	flag.StringVar(&opts.QuestionLangs, "question-langs", opts.QuestionLangs, "Comma-separated language codes (es, de, fr) to also render each question in, against the same English data")
+	flag.Int64Var(&opts.Seed, "seed", opts.Seed, "Seed for math/rand and faker so selections and names are reproducible; 0 uses the current time")
+	flag.IntVar(&opts.NeedleGap, "needle-gap", opts.NeedleGap, "If > 0, multi-name retrieval prompts query entries spaced exactly this many filler rows apart")
+	flag.IntVar(&opts.Updates, "updates", opts.Updates, "Number of \"Update: <name>'s age is now N\" lines after the data block in the incremental-update prompt")
+	flag.IntVar(&opts.AbsentNames, "absent-names", opts.AbsentNames, "Number of guaranteed-absent names mixed into the presence-check and mixed-age prompts")
+	flag.BoolVar(&opts.TimestampDir, "timestamp-dir", opts.TimestampDir, "Write into a new directory named after the output dir, a timestamp and the seed, preserving earlier runs")
+	flag.BoolVar(&opts.Clean, "clean", opts.Clean, "Remove the output directory before generating")
+	flag.BoolVar(&opts.Gzip, "gzip", opts.Gzip, "Gzip-compress every output file (prompts, answer key, manifest) and add a .gz suffix")
+	flag.IntVar(&opts.PreambleTokens, "preamble-tokens", opts.PreambleTokens, "Approximate number of tokens of unrelated filler text placed before each prompt's data")
+	flag.Float64Var(&opts.QueryFraction, "query-fraction", opts.QueryFraction, "Scale list queries with the dataset: 10-person lookups query this fraction of it (e.g. 0.01) and the others keep their ratio to 10; 0 keeps the absolute counts")
+	flag.StringVar(&opts.DecoyInstruction, "decoy-instruction", opts.DecoyInstruction, "Injected line placed inside the data block of the prompt-injection decoy prompt")
+	flag.BoolVar(&opts.ShuffleFields, "shuffle-fields", opts.ShuffleFields, "List each data row's labeled fields in its own random order, so rows can only be read by label, not by position")
+	flag.BoolVar(&opts.SingleLine, "single-line", opts.SingleLine, "Render the data block on one line, joining rows with '; ' instead of newlines")
+	flag.BoolVar(&opts.LineNumbers, "line-numbers", opts.LineNumbers, "Prefix each data row with its line number (e.g. '0001: '), enabling the line-lookup prompt")
+	flag.StringVar(&opts.ResultsCSV, "results-csv", opts.ResultsCSV, "Write one CSV row per prompt (metadata, plus the score when used with -grade) to this path")
+	flag.BoolVar(&opts.CSVBOM, "csv-bom", opts.CSVBOM, "Start the -results-csv file with a UTF-8 byte order mark, so Excel reads non-ASCII text correctly")
+	flag.StringVar(&opts.SummaryJSON, "summary-json", opts.SummaryJSON, "Also write the end-of-run summary as a JSON object to this path ('-' for stdout; all other output then goes to stderr)")
+	flag.IntVar(&opts.ReferenceYear, "reference-year", opts.ReferenceYear, "Add a Birth Year field computed as this year minus Age, enabling the birth-year prompt; 0 omits it")
+	flag.Float64Var(&opts.ModeSkew, "mode-skew", opts.ModeSkew, "Reassign this fraction of rows (0-1) to one city and one job title so the most-common prompts have a clear answer")
+	flag.StringVar(&opts.RandSource, "rand-source", opts.RandSource, "Random source: math (seeded, reproducible) or crypto (crypto/rand, ignores -seed)")
+	flag.Float64Var(&opts.CorruptionRate, "corruption-rate", opts.CorruptionRate, "Fraction of rows (0-1) to corrupt by swapping ages or misspelling cities; enables the corruption prompt")
+	flag.IntVar(&opts.VisibleRows, "visible-rows", opts.VisibleRows, "Show only this many rows (always including the queried people) in lookup prompts; 0 shows all")
+	flag.IntVar(&opts.TargetTokens, "target-tokens", opts.TargetTokens, "Size each lookup prompt to about this many estimated tokens by trimming data rows (always keeping the queried people) or adding filler text (0 = natural size)")
+	flag.Float64Var(&opts.TargetTolerance, "target-tolerance", opts.TargetTolerance, "Allowed relative distance from -target-tokens before a prompt is reported as off target")
+	flag.StringVar(&opts.TokenizerCmd, "tokenizer-cmd", opts.TokenizerCmd, "Shell command that reads a prompt on stdin and prints its token count; used instead of the characters-per-token estimate when set")
+	flag.IntVar(&opts.OverAge, "over-age", opts.OverAge, "Threshold for 'people over N' in the age-comparison prompt (within the -theme number range; 0 with -under-age 0 picks balanced thresholds)")
+	flag.IntVar(&opts.UnderAge, "under-age", opts.UnderAge, "Threshold for 'people under N' in the age-comparison prompt")
+	flag.IntVar(&opts.JoinDistance, "join-distance", opts.JoinDistance, "Rows between a person's row and their separate 'Age index' line in the split-attribute prompt")
+	flag.IntVar(&opts.MinCities, "min-cities", opts.MinCities, "Exit with an error if fewer unique cities than this are fetched")
+	flag.IntVar(&opts.MinTargetMatches, "min-target-matches", opts.MinTargetMatches, "Entries the target city or job title of a filter prompt must match; other targets are redrawn, then the prompt is skipped")
+	flag.StringVar(&opts.InputCSV, "input-csv", opts.InputCSV, "Load the entries from this CSV file (header with Name, Age, City and Job Title columns) instead of fetching cities and generating data")
+	flag.BoolVar(&opts.Force, "force", opts.Force, "Rewrite prompt files that already exist (by default non-empty ones are kept and the earlier run's data and answers reused, so an interrupted run can resume)")
+	flag.StringVar(&opts.GradeDir, "grade", opts.GradeDir, "Grade response_<desc>.txt files in this directory against the answer key instead of generating")
+	flag.StringVar(&opts.GradeAnswersDir, "grade-answers", opts.GradeAnswersDir, "Directory holding the answer key used by -grade")
+	flag.StringVar(&opts.CountExtract, "count-extract", opts.CountExtract, "Which integer -grade reads from a response to a count prompt: first or last")
+	flag.IntVar(&opts.ManyNeedles, "many-needles", opts.ManyNeedles, "Number of people queried by the many-needle stress prompt (e.g. 50, 100, 200)")
+	flag.BoolVar(&opts.ReorderQueries, "reorder-queries", opts.ReorderQueries, "Also write each list prompt with its queried names shuffled (suffix _reordered), sharing the same answer")
+	flag.BoolVar(&opts.FenceData, "fence-data", opts.FenceData, "Wrap the data block in a Markdown ``` code fence")
+	flag.StringVar(&opts.FenceLang, "fence-lang", opts.FenceLang, "Optional language tag for the -fence-data fence (e.g. text)")
+	flag.StringVar(&opts.OutputStyle, "output-style", opts.OutputStyle, "Prompt file style: flat (.txt) or chat (.json array of system and user messages)")
+	flag.StringVar(&opts.ValidateOnly, "validate-only", opts.ValidateOnly, "Re-check the prompts and answer key in this directory against its master_data.json instead of generating")
+	flag.IntVar(&opts.MaxAttemptsMultiplier, "max-attempts-multiplier", opts.MaxAttemptsMultiplier, "Name-generation retry budget as a multiple of the entry count (raise it for large datasets with many faker name collisions)")
+	flag.StringVar(&opts.Theme, "theme", opts.Theme, "Entity schema for the data: people (name, age, city, job title), products (name, price, warehouse, category) or books (title, year, author, genre); other themes skip the people-only prompts (see peopleOnly) and list them at startup")
+	flag.StringVar(&opts.CountryMode, "country-mode", opts.CountryMode, "Add a Country field to data rows: real (each city's country from the city API) or scrambled (each city given another city's country, to test trust in the data over world knowledge); empty leaves it out")
+	flag.StringVar(&opts.NameFormat, "name-format", opts.NameFormat, "How names are written everywhere (data rows, questions, answer key): first-last (Ann Lee), last-first (Lee, Ann) or initial-last (A. Lee)")
+	flag.StringVar(&opts.AgeStyle, "age-style", opts.AgeStyle, "How ages are written in data rows and reverse-lookup questions: digits (42) or words (forty-two)")
+	flag.StringVar(&opts.LabelStyle, "label-style", opts.LabelStyle, "Field labels in data rows: full (Name, Age, City, Job Title) or short (N, A, C, J)")
+	flag.StringVar(&opts.LineEnding, "line-ending", opts.LineEnding, "Line endings for generated files: lf or crlf")
+	flag.IntVar(&opts.MilestoneAge, "milestone-age", opts.MilestoneAge, "Age asked about in the \"how many years until <name> turns N\" prompt")
+	flag.BoolVar(&opts.SelfTest, "self-test", opts.SelfTest, "Before writing prompts, check on "+strconv.Itoa(promptgen.SELF_TEST_SAMPLES)+" sampled entries that forward (name->age) and reverse (age->names) answer keys agree, and exit if they do not")
+	flag.IntVar(&opts.QuestionsPerFile, "questions-per-file", opts.QuestionsPerFile, "Bundle this many questions that share the full data block into each prompt_bundle_<n> file, numbered Q1, Q2, ... (0 = one question per file)")
+	flag.BoolVar(&opts.Annotate, "annotate", opts.Annotate, "Append the expected answer to each prompt file as a trailing <!-- ANSWER: ... --> comment, for manual review only (-run strips it)")
+	flag.BoolVar(&opts.Nested, "nested", opts.Nested, "Also write a prompt whose data block is nested JSON, people grouped under their city key, asking which city one person is listed under")
+	flag.BoolVar(&opts.EmitBaseline, "emit-baseline", opts.EmitBaseline, "Also write prompt_<desc>.baseline.txt: the same question with no data block or preamble, to measure what the model answers from priors alone")
+	flag.IntVar(&opts.SamplePerBucket, "sample-per-bucket", opts.SamplePerBucket, "Keep only this many randomly chosen prompts per difficulty bucket (easy, medium, hard, very_hard) and remove the rest, for a quick representative run (0 = keep all)")
+	flag.Int64Var(&opts.MaxOutputBytes, "max-output-bytes", opts.MaxOutputBytes, "Stop writing prompt files once they would take more than this many bytes on disk (0 = no limit)")
+	flag.StringVar(&opts.RunDir, "run", opts.RunDir, "Send every prompt in this directory to -run-endpoint and save the replies to -responses-dir instead of generating")
+	flag.StringVar(&opts.RunEndpoint, "run-endpoint", opts.RunEndpoint, "OpenAI-compatible chat completions URL used by -run (the API key is read from $"+promptgen.RUN_API_KEY_ENV+")")
+	flag.StringVar(&opts.RunModel, "run-model", opts.RunModel, "Model name sent with each -run request")
+	flag.StringVar(&opts.ResponsesDir, "responses-dir", opts.ResponsesDir, "Directory -run writes response_<desc>.txt, the raw bodies and "+promptgen.RUN_LOG_FILENAME+" to")
+	flag.Float64Var(&opts.RateLimit, "rate-limit", opts.RateLimit, "Maximum -run requests per second across all workers (0 = unlimited)")
+	flag.IntVar(&opts.RateBurst, "rate-burst", opts.RateBurst, "Requests -run may send at once before -rate-limit applies")
+	flag.IntVar(&opts.MaxRetries, "max-retries", opts.MaxRetries, "Retries per prompt in -run after a 429, 5xx or transport error (Retry-After is honoured)")
+	flag.IntVar(&opts.Concurrency, "concurrency", opts.Concurrency, "Number of parallel workers for grading and -run")
+	flag.BoolVar(&opts.Progress, "progress", opts.Progress, "Show a progress indicator instead of per-item output (ignored when stdout is not a terminal)")
+}
+
+func main() {
+	flag.Parse()
+	opts.Out = os.Stdout
+	if opts.SummaryJSON == "-" {
+		// Progress and the text summary go to stderr, so stdout carries only the JSON summary.
+		opts.Out = os.Stderr
+	}
+	if strings.ContainsAny(opts.FenceLang, " \t\n`") {
+		log.Fatalf("Invalid -fence-lang %q: must be a single word without backticks.", opts.FenceLang)
+	}
+	schema, err := promptgen.SchemaForTheme(opts.Theme)
+	if err != nil {
+		log.Fatalf("Invalid -theme: %v", err)
+	}
+	if schema.Theme != "people" && (opts.QuestionLangs != "" || opts.ReferenceYear > 0) {
+		log.Fatalf("Invalid -theme %s: -question-langs and -reference-year are only supported with -theme people.", opts.Theme)
+	}
+	if opts.NameFormat != "first-last" && opts.NameFormat != "last-first" && opts.NameFormat != "initial-last" {
+		log.Fatalf("Invalid -name-format %q: must be first-last, last-first or initial-last.", opts.NameFormat)
+	}
+	if schema.Theme != "people" && opts.NameFormat != "first-last" {
+		log.Fatalf("Invalid -theme %s: -name-format is only supported with -theme people.", opts.Theme)
+	}
+	if opts.CountryMode != "" && opts.CountryMode != "real" && opts.CountryMode != "scrambled" {
+		log.Fatalf("Invalid -country-mode %q: must be real, scrambled or empty.", opts.CountryMode)
+	}
+	if opts.CountryMode != "" && schema.Theme != "people" {
+		log.Fatalf("Invalid -theme %s: -country-mode needs the city API, which only -theme people uses.", opts.Theme)
+	}
+	if opts.CountryMode != "" && opts.InputCSV != "" {
+		log.Fatalf("Invalid -country-mode %s: countries come from the city API, which -input-csv skips.", opts.CountryMode)
+	}
+	if opts.CountryMode != "" && opts.LabelStyle == "short" {
+		log.Fatalf("Invalid -country-mode %s: the short Country label would be \"C\", the same as City, under -label-style short.", opts.CountryMode)
+	}
+	if opts.AgeStyle != "digits" && opts.AgeStyle != "words" {
+		log.Fatalf("Invalid -age-style %q: must be digits or words.", opts.AgeStyle)
+	}
+	if opts.LabelStyle != "full" && opts.LabelStyle != "short" {
+		log.Fatalf("Invalid -label-style %q: must be full or short.", opts.LabelStyle)
+	}
+	if opts.LineEnding != "lf" && opts.LineEnding != "crlf" {
+		log.Fatalf("Invalid -line-ending %q: must be lf or crlf.", opts.LineEnding)
+	}
+	if opts.MilestoneAge <= promptgen.MIN_AGE || opts.MilestoneAge > promptgen.MAX_AGE {
+		log.Fatalf("Invalid -milestone-age %d: must be above %d and at most %d so some people are younger.", opts.MilestoneAge, promptgen.MIN_AGE, promptgen.MAX_AGE)
+	}
+	if opts.QuestionsPerFile < 0 {
+		log.Fatalf("Invalid -questions-per-file %d: must be 0 (one question per file) or positive.", opts.QuestionsPerFile)
+	}
+	if opts.SamplePerBucket < 0 {
+		log.Fatalf("Invalid -sample-per-bucket %d: must be 0 (keep all) or positive.", opts.SamplePerBucket)
+	}
+	if opts.SamplePerBucket > 0 && opts.QuestionsPerFile > 0 {
+		log.Fatalf("Invalid -sample-per-bucket %d: cannot be combined with -questions-per-file, whose bundles have no difficulty score.", opts.SamplePerBucket)
+	}
+	if opts.MaxOutputBytes < 0 {
+		log.Fatalf("Invalid -max-output-bytes %d: must be 0 (no limit) or positive.", opts.MaxOutputBytes)
+	}
+	if opts.OutputStyle != "flat" && opts.OutputStyle != "chat" {
+		log.Fatalf("Invalid -output-style %q: must be flat or chat.", opts.OutputStyle)
+	}
+	if opts.MaxAttemptsMultiplier < 1 {
+		log.Fatalf("Invalid -max-attempts-multiplier %d: must be >= 1.", opts.MaxAttemptsMultiplier)
+	}
+	if opts.ManyNeedles < 1 {
+		log.Fatalf("Invalid -many-needles %d: must be >= 1.", opts.ManyNeedles)
+	}
+	if strings.TrimSpace(opts.DecoyInstruction) == "" {
+		log.Fatalf("Invalid -decoy-instruction: must not be empty.")
+	}
+	if opts.Concurrency < 1 {
+		log.Fatalf("Invalid -concurrency %d: must be >= 1.", opts.Concurrency)
+	}
+	if opts.CountExtract != "first" && opts.CountExtract != "last" {
+		log.Fatalf("Invalid -count-extract %q: must be first or last.", opts.CountExtract)
+	}
+	if opts.GradeDir != "" {
+		if err := promptgen.Grade(opts); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		return
+	}
+	if opts.RunDir != "" {
+		if opts.RunEndpoint == "" {
+			log.Fatalf("Invalid -run: -run-endpoint is required.")
+		}
+		if opts.RateLimit < 0 || opts.RateBurst < 1 || opts.MaxRetries < 0 {
+			log.Fatalf("Invalid -run settings: -rate-limit must be >= 0, -rate-burst >= 1 and -max-retries >= 0.")
+		}
+		if err := promptgen.RunPrompts(opts); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		return
+	}
+	if opts.ValidateOnly != "" {
+		if err := promptgen.ValidateDir(opts); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		return
+	}
+	if opts.AnswerFormat != "json" && opts.AnswerFormat != "yaml" {
+		log.Fatalf("Invalid -answer-format %q: must be json or yaml.", opts.AnswerFormat)
+	}
+	if opts.AnswerListStyle != "json" && opts.AnswerListStyle != "comma" && opts.AnswerListStyle != "newline" {
+		log.Fatalf("Invalid -answer-list-style %q: must be json, comma or newline.", opts.AnswerListStyle)
+	}
+	if opts.BlankCityRate < 0 || opts.BlankCityRate > 1 {
+		log.Fatalf("Invalid -blank-city-rate %v: must be between 0 and 1.", opts.BlankCityRate)
+	}
+	if opts.FormatNoise < 0 || opts.FormatNoise > 1 {
+		log.Fatalf("Invalid -format-noise %v: must be between 0 and 1.", opts.FormatNoise)
+	}
+	if opts.Updates < 1 {
+		log.Fatalf("Invalid -updates %d: must be >= 1.", opts.Updates)
+	}
+	if opts.AbsentNames < 1 {
+		log.Fatalf("Invalid -absent-names %d: must be >= 1.", opts.AbsentNames)
+	}
+	if opts.NeedleGap < 0 {
+		log.Fatalf("Invalid -needle-gap %d: must be >= 0.", opts.NeedleGap)
+	}
+	if opts.ReferenceYear < 0 {
+		log.Fatalf("Invalid -reference-year %d: must be >= 0.", opts.ReferenceYear)
+	}
+	if opts.ModeSkew < 0 || opts.ModeSkew > 1 {
+		log.Fatalf("Invalid -mode-skew %v: must be between 0 and 1.", opts.ModeSkew)
+	}
+	if opts.RandSource != "math" && opts.RandSource != "crypto" {
+		log.Fatalf("Invalid -rand-source %q: must be math or crypto.", opts.RandSource)
+	}
+	if opts.RandSource == "crypto" && opts.Seed != 0 {
+		log.Fatalf("-seed cannot be combined with -rand-source crypto.")
+	}
+	if opts.CorruptionRate < 0 || opts.CorruptionRate > 1 {
+		log.Fatalf("Invalid -corruption-rate %v: must be between 0 and 1.", opts.CorruptionRate)
+	}
+	if opts.VisibleRows < 0 {
+		log.Fatalf("Invalid -visible-rows %d: must be >= 0.", opts.VisibleRows)
+	}
+	if opts.TargetTokens < 0 {
+		log.Fatalf("Invalid -target-tokens %d: must be 0 (natural size) or positive.", opts.TargetTokens)
+	}
+	if opts.TargetTokens > 0 && opts.VisibleRows > 0 {
+		log.Fatalf("Invalid -target-tokens %d: cannot be combined with -visible-rows, which also sets the row count.", opts.TargetTokens)
+	}
+	if opts.TargetTolerance <= 0 || opts.TargetTolerance >= 1 {
+		log.Fatalf("Invalid -target-tolerance %v: must be between 0 and 1.", opts.TargetTolerance)
+	}
+	if (opts.OverAge == 0) != (opts.UnderAge == 0) {
+		log.Fatalf("Invalid -over-age %d / -under-age %d: set both or neither.", opts.OverAge, opts.UnderAge)
+	}
+	if opts.OverAge != 0 && (opts.OverAge < schema.NumberMin || opts.OverAge > schema.NumberMax || opts.UnderAge < schema.NumberMin || opts.UnderAge > schema.NumberMax) {
+		log.Fatalf("Invalid -over-age %d / -under-age %d: must be between %d and %d, the %s range of -theme %s.", opts.OverAge, opts.UnderAge, schema.NumberMin, schema.NumberMax, strings.ToLower(schema.NumberLabel), schema.Theme)
+	}
+	if opts.JoinDistance < 1 {
+		log.Fatalf("Invalid -join-distance %d: must be >= 1.", opts.JoinDistance)
+	}
+	if opts.QueryFraction < 0 || opts.QueryFraction > 1 {
+		log.Fatalf("Invalid -query-fraction %v: must be between 0 and 1.", opts.QueryFraction)
+	}
+	if opts.MinTargetMatches < 1 {
+		log.Fatalf("Invalid -min-target-matches %d: must be at least 1.", opts.MinTargetMatches)
+	}
+	if opts.MinCities < 1 || opts.MinCities > promptgen.TARGET_UNIQUE_CITIES {
+		log.Fatalf("Invalid -min-cities %d: must be between 1 and %d.", opts.MinCities, promptgen.TARGET_UNIQUE_CITIES)
+	}
+	if opts.MarkerInstruction && (opts.DataHeader == "" || opts.DataFooter == "") {
+		log.Fatal("-marker-instruction requires both -data-header and -data-footer.")
+	}
+
+	if err := promptgen.Generate(opts); err != nil {
+		log.Fatalf("Error: %v. Exiting.", err)
+	}
+}
@@ -0,0 +1,59 @@
+package promptgen
+
+import (
+	"fmt"
+	"io"
+	"testing"
+)
+
+var benchCities = []string{"Tartu", "Osaka", "Malmo", "Porto", "Ghent", "Lyon", "Graz", "Turku"}
+
+func benchData(b *testing.B, n int) []PersonEntry {
+	b.Helper()
+	data, err := newBareGenerator(b).generateRandomData(n, benchCities)
+	if err != nil {
+		b.Fatalf("generateRandomData: %v", err)
+	}
+	return data
+}
+
+func BenchmarkGenerateRandomData(b *testing.B) {
+	for _, n := range []int{100, 1000, 5000} {
+		b.Run(fmt.Sprintf("entries=%d", n), func(b *testing.B) {
+			g := newBareGenerator(b)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				g.rng = seedRandomSources(int64(i))
+				if _, err := g.generateRandomData(n, benchCities); err != nil {
+					b.Fatalf("generateRandomData: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkFormatDataBlock(b *testing.B) {
+	data := benchData(b, NUM_ENTRIES)
+	g := newBareGenerator(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		g.formatDataBlock(data, g.dataFormat)
+	}
+}
+
+func BenchmarkRenderPrompt(b *testing.B) {
+	data := benchData(b, NUM_ENTRIES)
+	templateData := map[string]interface{}{
+		"DataBlock":           newBareGenerator(b).formatDataBlock(data, DataBlockFormat{FieldDelimiter: " | ", KeyValueSeparator: ": "}),
+		"QueryItemsFormatted": "- " + data[0].Name + "\n- " + data[1].Name,
+	}
+	templateText := `Here is the list:\n{{.DataBlock}}\n\nFrom the list above, what are the ages for:\n{{.QueryItemsFormatted}}`
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := renderPrompt(io.Discard, "bench", templateText, templateData, "", ""); err != nil {
+			b.Fatalf("renderPrompt: %v", err)
+		}
+	}
+}
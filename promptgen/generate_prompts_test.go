@@ -0,0 +1,586 @@
+package promptgen
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFetchCitiesFromAPI(t *testing.T) {
+	tests := []struct {
+		name         string
+		handler      func(call int64, w http.ResponseWriter)
+		timeout      time.Duration // Client timeout; zero keeps the test client's default
+		numToFetch   int
+		targetUnique int
+		want         []string
+		wantErr      error
+		wantCalls    int64 // Requests the fetch must make; zero skips the check
+	}{
+		{
+			name: "success",
+			handler: func(call int64, w http.ResponseWriter) {
+				fmt.Fprintf(w, `{"city": "City%d", "country": "Country%d"}`, call, call)
+			},
+			numToFetch:   10,
+			targetUnique: 3,
+			want:         []string{"City1", "City2", "City3"},
+		},
+		{
+			name: "non-200",
+			handler: func(call int64, w http.ResponseWriter) {
+				http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			},
+			numToFetch:   2,
+			targetUnique: 2,
+			wantErr:      ErrNoCities,
+		},
+		{
+			name: "malformed JSON",
+			handler: func(call int64, w http.ResponseWriter) {
+				fmt.Fprint(w, `{"city": `)
+			},
+			numToFetch:   2,
+			targetUnique: 2,
+			wantErr:      ErrNoCities,
+		},
+		{
+			name: "empty city names",
+			handler: func(call int64, w http.ResponseWriter) {
+				fmt.Fprint(w, `{"city": "", "country": "Nowhere"}`)
+			},
+			numToFetch:   3,
+			targetUnique: 2,
+			wantErr:      ErrNoCities,
+			wantCalls:    3,
+		},
+		{
+			name: "empty city names skipped",
+			handler: func(call int64, w http.ResponseWriter) {
+				if call%2 == 1 {
+					fmt.Fprint(w, `{"city": ""}`)
+					return
+				}
+				fmt.Fprintf(w, `{"city": "City%d", "country": "Country%d"}`, call, call)
+			},
+			numToFetch:   6,
+			targetUnique: 2,
+			want:         []string{"City2", "City4"},
+			wantCalls:    4,
+		},
+		{
+			name: "timeouts",
+			handler: func(call int64, w http.ResponseWriter) {
+				time.Sleep(200 * time.Millisecond)
+				fmt.Fprint(w, `{"city": "Late", "country": "Nowhere"}`)
+			},
+			timeout:      20 * time.Millisecond,
+			numToFetch:   2,
+			targetUnique: 1,
+			wantErr:      ErrNoCities,
+			wantCalls:    2,
+		},
+		{
+			name: "timeout retried",
+			handler: func(call int64, w http.ResponseWriter) {
+				if call == 1 {
+					time.Sleep(200 * time.Millisecond)
+				}
+				fmt.Fprintf(w, `{"city": "City%d", "country": "Country%d"}`, call, call)
+			},
+			timeout:      20 * time.Millisecond,
+			numToFetch:   3,
+			targetUnique: 1,
+			want:         []string{"City2"},
+			wantCalls:    2,
+		},
+		{
+			name: "too few unique cities",
+			handler: func(call int64, w http.ResponseWriter) {
+				fmt.Fprintf(w, `{"city": "City%d", "country": "Nowhere"}`, call%2)
+			},
+			numToFetch:   6,
+			targetUnique: 5,
+			want:         []string{"City1", "City0"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int64
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				tt.handler(atomic.AddInt64(&calls, 1), w)
+			}))
+			defer server.Close()
+			client := server.Client()
+			if tt.timeout > 0 {
+				client.Timeout = tt.timeout
+			}
+
+			got, err := newBareGenerator(t).fetchCitiesFromAPI(client, server.URL, tt.numToFetch, tt.targetUnique)
+			if n := atomic.LoadInt64(&calls); tt.wantCalls > 0 && n != tt.wantCalls {
+				t.Errorf("made %d requests, want %d", n, tt.wantCalls)
+			}
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("cities = %v, want %v", got, tt.want)
+			}
+			if n := atomic.LoadInt64(&calls); n > int64(tt.numToFetch) {
+				t.Errorf("made %d requests, want at most %d", n, tt.numToFetch)
+			}
+		})
+	}
+}
+
+func TestFormatSplitAttributeBlockPlacesEveryIndexLine(t *testing.T) {
+	const distance = 3
+	format := DataBlockFormat{FieldDelimiter: " | ", KeyValueSeparator: ": "}
+	data := make([]PersonEntry, 2*distance+1)
+	split := make(map[string]bool, len(data))
+	for i := range data {
+		data[i] = PersonEntry{Name: fmt.Sprintf("Person %d", i), Age: 20 + i, City: "Tartu", JobTitle: "Chef"}
+		split[data[i].Name] = true
+	}
+	g := newBareGenerator(t)
+	block, indexRows := g.formatSplitAttributeBlock(data, format, split, distance)
+	for i, entry := range data {
+		if !strings.Contains(block, g.splitIndexLine(entry, format)) {
+			t.Errorf("block is missing the index line for %s", entry.Name)
+		}
+		if row := indexRows[entry.Name]; row < 0 || row >= len(data) || (row != i+distance && row != i-distance) {
+			t.Errorf("index row for %s = %d, want %d rows away inside the list", entry.Name, row, distance)
+		}
+	}
+}
+
+func TestCheckBirthYears(t *testing.T) {
+	year := func(y int) *int { return &y }
+	tests := []struct {
+		name  string
+		entry PersonEntry
+		want  int
+	}{
+		{name: "agrees", entry: PersonEntry{Name: "A", Age: 30, BirthYear: year(1990)}},
+		{name: "disagrees", entry: PersonEntry{Name: "B", Age: 30, BirthYear: year(1991)}, want: 1},
+		{name: "unset", entry: PersonEntry{Name: "C", Age: 30}, want: 1},
+		{name: "year zero is set", entry: PersonEntry{Name: "D", Age: 2020, BirthYear: year(0)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := checkBirthYears([]PersonEntry{tt.entry}, 2020); len(got) != tt.want {
+				t.Errorf("checkBirthYears = %v, want %d problem(s)", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnswerKeyRoundTrip(t *testing.T) {
+	answers := []PromptAnswer{
+		{Desc: "01_standard_retrieval_10", File: "prompt_01_standard_retrieval_10.txt", Expected: map[string]int{"Queen Weber": 49, "Dan Daugherty": 41},
+			AnswerRegexes: []string{`(?i)Queen Weber\D{0,40}\b49\b`}},
+		{Desc: "12_count_job_title", File: "prompt_12_count_job_title.txt", Lang: "de", Expected: 17, ResponseFormat: RESPONSE_FORMAT_COUNT},
+		{Desc: "11_filter_city_get_name_job", File: "prompt_11_filter_city_get_name_job.txt", Reordered: true,
+			Expected: testPeople(1)},
+		{Desc: "39_many_needles", File: "prompt_39_many_needles.txt", Expected: map[string]interface{}{"names": []string{"A", "B"}},
+			NeedleGap: 3, Needles: []Needle{{Name: "A", Index: 0}, {Name: "B", Index: 4}}, BaselineFile: "prompt_39_many_needles.baseline.txt"},
+	}
+	// Decoded numbers are int (YAML) or float64 (JSON) and records become maps, so both sides are
+	// compared as generic JSON.
+	normalize := func(v interface{}) string {
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var generic interface{}
+		if err := json.Unmarshal(encoded, &generic); err != nil {
+			t.Fatal(err)
+		}
+		encoded, _ = json.Marshal(generic)
+		return string(encoded)
+	}
+	want := normalize(answers)
+	for _, format := range []string{"json", "yaml"} {
+		t.Run(format, func(t *testing.T) {
+			dir := t.TempDir()
+			path, err := newBareGenerator(t).writeAnswerKey(dir, answers, format)
+			if err != nil {
+				t.Fatalf("writeAnswerKey: %v", err)
+			}
+			if filepath.Base(path) != ANSWER_KEY_BASENAME+"."+format {
+				t.Errorf("answer key written to %s, want %s.%s", path, ANSWER_KEY_BASENAME, format)
+			}
+			loaded, err := loadAnswerKey(dir)
+			if err != nil {
+				t.Fatalf("loadAnswerKey: %v", err)
+			}
+			if got := normalize(loaded); got != want {
+				t.Errorf("round trip changed the key:\n got %s\nwant %s", got, want)
+			}
+			if ages, ok := expectedAges(loaded[0].Expected); !ok || ages["Queen Weber"] != 49 {
+				t.Errorf("expectedAges = %v, %v; want Queen Weber 49", ages, ok)
+			}
+			if count, ok := expectedCount(loaded[1].Expected); !ok || count != 17 {
+				t.Errorf("expectedCount = %d, %v; want 17", count, ok)
+			}
+		})
+	}
+}
+
+func TestFocusIndicesOnSmallDatasets(t *testing.T) {
+	tests := []struct {
+		name         string
+		indices      []int
+		n            int
+		want1, want2 int
+		wantOK       bool
+	}{
+		{name: "large dataset", indices: []int{1, 8}, n: 10, want1: 1, want2: 8, wantOK: true},
+		{name: "indices from a 2-entry dataset", indices: []int{1, 0}, n: 2, want1: 1, want2: 0, wantOK: true},
+		{name: "indices clamped onto one entry", indices: []int{1, NUM_ENTRIES - 2}, n: 2, want1: 0, want2: 1, wantOK: true},
+		{name: "one entry", indices: []int{1, -1}, n: 1, wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idx1, idx2, ok := focusIndices(tt.indices, tt.n)
+			if ok != tt.wantOK {
+				t.Fatalf("focusIndices(%v, %d) ok = %v, want %v", tt.indices, tt.n, ok, tt.wantOK)
+			}
+			if ok && (idx1 != tt.want1 || idx2 != tt.want2) {
+				t.Errorf("focusIndices(%v, %d) = %d, %d; want %d, %d", tt.indices, tt.n, idx1, idx2, tt.want1, tt.want2)
+			}
+		})
+	}
+}
+
+func TestSeedRepeatsFakerNames(t *testing.T) {
+	draw := func() [2]string {
+		seedRandomSources(42)
+		var names [2]string
+		for i := range names {
+			name, err := fakerPersonName()
+			if err != nil {
+				t.Fatalf("fakerPersonName: %v", err)
+			}
+			names[i] = name
+		}
+		return names
+	}
+	first, second := draw(), draw()
+	if first != second {
+		t.Errorf("seed 42 gave %v, then %v", first, second)
+	}
+	if first[0] == first[1] {
+		t.Errorf("seed 42 drew the same name twice: %v", first)
+	}
+}
+
+func TestCRLFLineEndingInOutputBytes(t *testing.T) {
+	g := newBareGenerator(t)
+	g.opts.LineEnding = "crlf"
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "LF only", content: "List:\nName: A\nName: B\n", want: "List:\r\nName: A\r\nName: B\r\n"},
+		{name: "already CRLF", content: "List:\r\nName: A\r\n", want: "List:\r\nName: A\r\n"},
+		{name: "mixed", content: "List:\r\nName: A\nName: B", want: "List:\r\nName: A\r\nName: B"},
+		{name: "no newline", content: "Name: A", want: "Name: A"},
+	}
+	for _, gzipped := range []bool{false, true} {
+		g.opts.Gzip = gzipped
+		for _, tt := range tests {
+			t.Run(fmt.Sprintf("%s gzip=%v", tt.name, gzipped), func(t *testing.T) {
+				path := filepath.Join(t.TempDir(), "prompt_test.txt")
+				if _, err := g.writeOutputFile(path, []byte(tt.content)); err != nil {
+					t.Fatalf("writeOutputFile: %v", err)
+				}
+				got, err := readOutputFile(path)
+				if err != nil {
+					t.Fatalf("readOutputFile: %v", err)
+				}
+				if string(got) != tt.want {
+					t.Errorf("file holds %q, want %q", got, tt.want)
+				}
+				if lf, crlf := strings.Count(string(got), "\n"), strings.Count(string(got), "\r\n"); lf != crlf {
+					t.Errorf("%d of %d line feeds lack a carriage return", lf-crlf, lf)
+				}
+			})
+		}
+	}
+}
+
+func TestFetchCitiesFromAPIRecordsHomonyms(t *testing.T) {
+	responses := []string{
+		`{"city": "Springfield", "country": "United States"}`,
+		`{"city": "Tartu", "country": "Estonia"}`,
+		`{"city": "Springfield", "country": "Canada"}`,
+		`{"city": "Springfield", "country": "United States"}`,
+	}
+	var calls int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, responses[(atomic.AddInt64(&calls, 1)-1)%int64(len(responses))])
+	}))
+	defer server.Close()
+
+	g := newBareGenerator(t)
+	cities, err := g.fetchCitiesFromAPI(server.Client(), server.URL, len(responses), len(responses))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"Springfield", "Tartu"}; !reflect.DeepEqual(cities, want) {
+		t.Errorf("cities = %v, want %v", cities, want)
+	}
+	if got := g.countries["Springfield"]; got != "United States" {
+		t.Errorf("countries[Springfield] = %q, want the first country reported", got)
+	}
+	if want := map[string][]string{"Springfield": {"United States", "Canada"}}; !reflect.DeepEqual(g.homonyms, want) {
+		t.Errorf("homonyms = %v, want %v", g.homonyms, want)
+	}
+}
+
+func TestCheckPromptDescs(t *testing.T) {
+	tests := []struct {
+		name    string
+		descs   []string
+		wantErr string
+	}{
+		{name: "distinct", descs: []string{"01_standard_retrieval_10", "02_different_phrasing_10"}},
+		{name: "duplicate", descs: []string{"01_a", "01_a"}, wantErr: "duplicate"},
+		{name: "same filename", descs: []string{"01/a", "01_a"}, wantErr: "duplicate"},
+		{name: "bundle prefix", descs: []string{"bundle_01"}, wantErr: "reserved"},
+		{name: "bundle prefix after sanitizing", descs: []string{".bundle_02"}, wantErr: "reserved"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configs := make([]PromptConfig, len(tt.descs))
+			for i, desc := range tt.descs {
+				configs[i] = PromptConfig{Desc: desc}
+			}
+			err := checkPromptDescs(configs)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCSVByteOrderMark(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "people.csv")
+	content := UTF8_BOM + "\"Name\",Age,City,Job Title\nZoë Ångström,34,Malmö,Chef\n"
+	if err := os.WriteFile(input, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	data, err := newBareGenerator(t).loadCSVData(input)
+	if err != nil {
+		t.Fatalf("loadCSVData with a BOM: %v", err)
+	}
+	if want := []PersonEntry{{Name: "Zoë Ångström", Age: 34, City: "Malmö", JobTitle: "Chef"}}; !reflect.DeepEqual(data, want) {
+		t.Errorf("loaded %v, want %v", data, want)
+	}
+
+	records := []PromptRecord{{Desc: "01_standard_retrieval_10", File: "prompt_01_standard_retrieval_10.txt", Type: "Lookup"}}
+	for _, bom := range []bool{false, true} {
+		output := filepath.Join(dir, fmt.Sprintf("results_%v.csv", bom))
+		if err := writeResultsCSV(output, records, nil, bom); err != nil {
+			t.Fatalf("writeResultsCSV: %v", err)
+		}
+		written, err := os.ReadFile(output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.HasPrefix(string(written), UTF8_BOM); got != bom {
+			t.Errorf("-csv-bom %v: file starts with a BOM = %v", bom, got)
+		}
+	}
+}
+
+func TestVaryingDelimiterFormatsUnderSingleLine(t *testing.T) {
+	tests := []struct {
+		name         string
+		rowSeparator string
+		want         []string
+	}{
+		{name: "newline rows", want: varyingDelimiters},
+		{name: "single-line rows", rowSeparator: "; ", want: []string{" | ", ",", "\t"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			formats, err := newBareGenerator(t).varyingDelimiterFormats(DataBlockFormat{FieldDelimiter: " | ", KeyValueSeparator: ": ", RowSeparator: tt.rowSeparator})
+			if err != nil {
+				t.Fatalf("varyingDelimiterFormats: %v", err)
+			}
+			var got []string
+			for _, format := range formats {
+				got = append(got, format.FieldDelimiter)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("delimiters = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigsOverVisibleRows(t *testing.T) {
+	configs := []PromptConfig{
+		{Desc: "01_standard_retrieval_10", QueryCount: 10},
+		{Desc: "39_many_needles", QueryCount: 100, FixedQueryCount: true},
+		{Desc: "10_reverse_lookup_age_2", QueryCount: 50, IsReverseLookup: true},
+	}
+	tests := []struct {
+		rows int
+		want []string
+	}{
+		{rows: 100, want: []string{}},
+		{rows: 20, want: []string{"39_many_needles (100)"}},
+		{rows: 5, want: []string{"01_standard_retrieval_10 (10)", "39_many_needles (100)"}},
+	}
+	for _, tt := range tests {
+		if got := configsOverVisibleRows(configs, tt.rows); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("configsOverVisibleRows(%d) = %v, want %v", tt.rows, got, tt.want)
+		}
+	}
+}
+
+func TestMatchesOutputFile(t *testing.T) {
+	g := newBareGenerator(t)
+	g.opts.LineEnding = "crlf"
+
+	content := []byte("List:\nName: A\n")
+	for _, gzipped := range []bool{false, true} {
+		g.opts.Gzip = gzipped
+		t.Run(fmt.Sprintf("gzip=%v", gzipped), func(t *testing.T) {
+			path, err := g.writeOutputFile(filepath.Join(t.TempDir(), "prompt_test.txt"), content)
+			if err != nil {
+				t.Fatalf("writeOutputFile: %v", err)
+			}
+			if !g.matchesOutputFile(path, content) {
+				t.Errorf("%s does not match the content it was written from", path)
+			}
+			if g.matchesOutputFile(path, []byte("List:\nName: B\n")) {
+				t.Errorf("%s matches different content", path)
+			}
+		})
+	}
+}
+
+func TestLoadResumeStateWithoutAnswerKey(t *testing.T) {
+	g := newBareGenerator(t)
+	dir := t.TempDir()
+	data := []PersonEntry{{Name: "Ada Lovelace", Age: 36, City: "London", JobTitle: "Engineer"}}
+	if _, err := g.writeMasterData(dir, data); err != nil {
+		t.Fatalf("writeMasterData: %v", err)
+	}
+	if _, err := g.writeManifest(dir, RunManifest{Seed: 7, RandSource: "math", NumEntries: len(data)}); err != nil {
+		t.Fatalf("writeManifest: %v", err)
+	}
+	state, err := loadResumeState(dir)
+	if err != nil {
+		t.Fatalf("loadResumeState: %v", err)
+	}
+	if len(state.answers) != 0 {
+		t.Errorf("got %d answers, want none", len(state.answers))
+	}
+	if state.manifest.Seed != 7 || len(state.data) != 1 {
+		t.Errorf("got seed %d and %d entries, want seed 7 and 1 entry", state.manifest.Seed, len(state.data))
+	}
+}
+
+func TestResolveQueryCountsKeepsRatios(t *testing.T) {
+	configs := []PromptConfig{
+		{Desc: "01_standard_retrieval_10", QueryCount: 10},
+		{Desc: "03_fewer_items_5", QueryCount: 5},
+		{Desc: "04_more_items_15", QueryCount: 15},
+		{Desc: "06_reverse_lookup_name", QueryCount: 2, IsReverseLookup: true},
+		{Desc: "39_many_needles", QueryCount: 100, FixedQueryCount: true},
+	}
+	tests := []struct {
+		name       string
+		fraction   float64
+		numEntries int
+		want       []int
+	}{
+		{name: "scaled up", fraction: 0.02, numEntries: 1500, want: []int{30, 15, 45, 2, 100}},
+		{name: "scaled down", fraction: 0.01, numEntries: 300, want: []int{3, 2, 5, 2, 100}},
+		{name: "at least one", fraction: 0.001, numEntries: 100, want: []int{1, 1, 1, 2, 100}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolved := resolveQueryCounts(configs, tt.fraction, tt.numEntries)
+			got := make([]int, len(resolved))
+			for i, config := range resolved {
+				got[i] = config.QueryCount
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("query counts = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSerializeListAnswer(t *testing.T) {
+	records := testPeople(1)
+	expected := map[string]interface{}{
+		"names":   []string{"Queen Weber", "Dan Daugherty"},
+		"count":   2,
+		"matches": records,
+	}
+	tests := []struct {
+		style      string
+		nameFormat string
+		want       interface{}
+	}{
+		{style: "json", want: expected["names"]},
+		{style: "comma", want: "Queen Weber, Dan Daugherty"},
+		{style: "comma", nameFormat: "last-first", want: "Queen Weber; Dan Daugherty"},
+		{style: "newline", want: "Queen Weber\nDan Daugherty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.style+" "+tt.nameFormat, func(t *testing.T) {
+			g := newBareGenerator(t)
+			if tt.nameFormat != "" {
+				g.opts.NameFormat = tt.nameFormat
+			}
+			got, ok := g.serializeListAnswer(expected, tt.style).(map[string]interface{})
+			if !ok {
+				t.Fatalf("serializeListAnswer returned %T, want a map", got)
+			}
+			if !reflect.DeepEqual(got["names"], tt.want) {
+				t.Errorf("names = %#v, want %#v", got["names"], tt.want)
+			}
+			if got["count"] != 2 {
+				t.Errorf("count = %#v, want it unchanged", got["count"])
+			}
+			if !reflect.DeepEqual(got["matches"], records) {
+				t.Errorf("records = %#v, want them kept as records", got["matches"])
+			}
+			if top := g.serializeListAnswer(records, tt.style); !reflect.DeepEqual(top, records) {
+				t.Errorf("top-level records = %#v, want them kept as records", top)
+			}
+		})
+	}
+}
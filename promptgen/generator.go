@@ -0,0 +1,1439 @@
+package promptgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- Prompt Generator ---
+// Generator holds one run's state: its Options, the -theme schema, the random source and the
+// dataset it renders prompt configs over, plus the answers and manifest records collected so far.
+// Generate (the package function) drives one for a whole run; tests build one with NewGenerator
+// and SetData and capture the rendered files through Write.
+type Generator struct {
+	// Write stores one rendered file at path and returns where it ended up (writeOutputFile may
+	// add .gz). Nil writes to disk.
+	Write func(path string, content []byte) (string, error)
+
+	opts   Options
+	out    io.Writer // opts.out()
+	schema *Schema   // Set by -theme
+	// rng backs every random selection. It is reseeded for each prompt config (see configSeed)
+	// unless -rand-source is crypto.
+	rng randomSource
+	// countries records the country the city API reported for each fetched city.
+	countries countryTable
+	// homonyms lists every country the API reported for a city name it placed in more than one
+	// country. countries keeps only the first, and entries store just the name, so such a city is
+	// ambiguous under -country-mode.
+	homonyms        map[string][]string
+	outputBytes     int64 // Bytes writeOutputFile has put on disk, checked against -max-output-bytes
+	tokenizerFailed bool  // Set by the first -tokenizer-cmd failure; every later count uses the heuristic
+
+	masterData        []PersonEntry
+	allNames          []string
+	entryByName       map[string]PersonEntry
+	indexByName       map[string]int
+	fetchedCities     []string
+	corruptions       []Corruption
+	dataFormat        DataBlockFormat
+	dataBlockString   string
+	preamble          string
+	markerInstruction string
+	systemPromptText  string
+	systemPromptHash  string
+	promptPrefix      string
+	promptExt         string
+	langs             []string
+	outputDir         string
+	runSeed           int64
+	earlier           *resumeState // Set when resuming an earlier run in outputDir
+	progress          *progressBar
+
+	answers           []PromptAnswer
+	promptRecords     []PromptRecord
+	bundled           []bundledQuestion // Held back for -questions-per-file
+	integrityFailures []string
+	generatedCount    int
+	skippedCount      int
+	budgetSkipped     int             // Prompt files not written once -max-output-bytes was reached
+	written           map[string]bool // Names of the files this run wrote, as listed in the answer key
+}
+
+// NewGenerator prepares a Generator for opts: it resolves the -theme schema and data block
+// format, loads the -system-prompt file and seeds the random source from opts.Seed (the current
+// time when 0) unless opts.RandSource is crypto. Prompt file paths point into opts.OutputDir.
+func NewGenerator(opts Options) (*Generator, error) {
+	schema, err := SchemaForTheme(opts.Theme)
+	if err != nil {
+		return nil, err
+	}
+	g := &Generator{
+		opts:      opts,
+		out:       opts.out(),
+		schema:    schema,
+		countries: countryTable{},
+		homonyms:  map[string][]string{},
+		promptExt: ".txt",
+		outputDir: opts.OutputDir,
+		written:   make(map[string]bool),
+	}
+	g.dataFormat = DataBlockFormat{
+		FieldDelimiter:    g.compactSeparator(strings.ReplaceAll(opts.FormatDelimiter, `\t`, "\t")),
+		KeyValueSeparator: g.compactSeparator(strings.ReplaceAll(opts.FormatKVSeparator, `\t`, "\t")),
+		LineNumbers:       opts.LineNumbers,
+		AgeWords:          opts.AgeStyle == "words",
+		ShortLabels:       opts.LabelStyle == "short",
+		ShuffleFields:     opts.ShuffleFields,
+		Noise:             opts.FormatNoise,
+	}
+	if opts.SingleLine {
+		g.dataFormat.RowSeparator = "; "
+	}
+	if err := g.dataFormat.validate(); err != nil {
+		return nil, fmt.Errorf("invalid data block format: %w", err)
+	}
+	if opts.SystemPrompt != "" {
+		g.systemPromptText, g.systemPromptHash, err = loadSystemPrompt(opts.SystemPrompt)
+		if err != nil {
+			return nil, fmt.Errorf("loading system prompt: %w", err)
+		}
+		g.promptPrefix = systemPromptBlockFor(g.systemPromptText)
+	}
+	if opts.OutputStyle == "chat" {
+		g.promptPrefix, g.promptExt = "", ".json" // The system prompt becomes its own message
+	}
+	if opts.RandSource == "crypto" {
+		g.rng = useCryptoRandomSources()
+	} else {
+		g.runSeed = opts.Seed
+		if g.runSeed == 0 {
+			g.runSeed = time.Now().UnixNano()
+		}
+		g.rng = seedRandomSources(g.runSeed)
+	}
+	return g, nil
+}
+
+// SetData makes data the dataset g renders prompts over. It formats the full data block and the
+// -preamble-tokens filler, both of which draw from g's random source.
+func (g *Generator) SetData(data []PersonEntry) {
+	g.masterData = data
+	g.allNames = make([]string, len(data))
+	g.entryByName = make(map[string]PersonEntry, len(data))
+	g.indexByName = make(map[string]int, len(data))
+	g.fetchedCities = distinctCities(data)
+	for i, entry := range data {
+		g.allNames[i] = entry.Name
+		g.entryByName[entry.Name] = entry
+		g.indexByName[entry.Name] = i
+	}
+	g.dataBlockString = g.wrapDataBlock(g.formatDataBlock(data, g.dataFormat), g.opts.DataHeader, g.opts.DataFooter)
+	g.preamble = g.buildPreamble(g.opts.PreambleTokens)
+	g.markerInstruction = ""
+	if g.opts.MarkerInstruction {
+		g.markerInstruction = fmt.Sprintf("\n\nOnly use the data between the '%s' and '%s' markers.", g.opts.DataHeader, g.opts.DataFooter)
+	}
+}
+
+// Generate renders config and returns the answers and manifest records of the files it produced.
+func (g *Generator) Generate(config PromptConfig) ([]PromptAnswer, []PromptRecord) {
+	answerCount, recordCount := len(g.answers), len(g.promptRecords)
+	g.generate(config)
+	return g.answers[answerCount:], g.promptRecords[recordCount:]
+}
+
+func (g *Generator) writeFile(path string, content []byte) (string, error) {
+	write := g.Write
+	if write == nil {
+		write = g.writeOutputFile
+	}
+	written, err := write(path, content)
+	if err == nil {
+		g.written[filepath.Base(written)] = true
+	}
+	return written, err
+}
+
+// generate renders every language and reordering variant of config. A config that cannot be
+// generated from this data is skipped with a warning.
+func (g *Generator) generate(config PromptConfig) {
+	promptSeed := int64(0)
+	if g.opts.RandSource == "math" {
+		promptSeed = configSeed(g.runSeed, config.Desc)
+		g.rng = seedRandomSources(promptSeed)
+	}
+	// --- Start File Writing Logic ---
+	templateData := map[string]interface{}{}
+	promptDataBlock := g.dataBlockString // Configs that render the data differently override this
+	configPreamble := g.preamble         // -target-tokens may lengthen it
+	sizedToTarget := false
+	blockFormats := []DataBlockFormat{g.dataFormat}
+	canGenerate := true
+	var expected interface{}
+	var queriedNames []string // Checked against the data block before writing
+	var queriedAges []int
+	matchCount := -1 // Defaults to the number of queried items when a branch leaves it unset
+	var needles []Needle
+	positionIndex, positionTotal := g.indexByName, len(g.masterData) // Row positions used for NeedlePositions
+	var queryItems []string                                          // The list rendered into QueryItemsFormatted, if any
+	nonExistentName := ""
+	if config.NeedsAbsentName {
+		name, err := g.generateAbsentName(g.entryByName)
+		if err != nil {
+			log.Printf("Warning: %v for %s. Skipping.", err, config.Desc)
+			return
+		}
+		nonExistentName = name
+		templateData["NonExistentName"] = nonExistentName
+	}
+
+	// Populate templateData based on config type
+	// START POPULATE BLOCK
+	if config.QueryCount > 0 {
+		minRequiredData := config.QueryCount
+		if config.IsReverseLookup {
+			minRequiredData = 2
+		}
+		if config.IsCombinedRequest {
+			minRequiredData = 3
+		}
+		if len(g.masterData) < minRequiredData {
+			log.Printf("Warning: Not enough data (%d) for query type in %s (needs %d). Skipping.", len(g.masterData), config.Desc, minRequiredData)
+			canGenerate = false
+		} else {
+			selectedNames := g.randomSampleNames(g.allNames, config.QueryCount)
+			isPlainRetrieval := !config.IsReverseLookup && !config.IsCombinedRequest
+			if g.opts.NeedleGap > 0 && isPlainRetrieval {
+				needles = g.sampleSpacedEntries(g.masterData, config.QueryCount, g.opts.NeedleGap)
+				if needles == nil {
+					log.Printf("Warning: %d needles with a gap of %d rows do not fit in %d entries for %s. Skipping.", config.QueryCount, g.opts.NeedleGap, len(g.masterData), config.Desc)
+					return
+				}
+				selectedNames = make([]string, len(needles))
+				for i, needle := range needles {
+					selectedNames[i] = needle.Name
+				}
+			}
+			queryItems = g.setQueryItems(templateData, selectedNames)
+			expected = agesForNames(selectedNames, g.entryByName)
+			queriedNames = selectedNames
+			if config.IsReverseLookup {
+				selectedEntries := g.randomSampleEntries(g.masterData, 2)
+				templateData["QueryAge1"] = g.dataFormat.age(selectedEntries[0].Age)
+				templateData["QueryAge2"] = g.dataFormat.age(selectedEntries[1].Age)
+				queriedNames = nil
+				queriedAges = []int{selectedEntries[0].Age, selectedEntries[1].Age}
+				expected = map[string][]string{
+					strconv.Itoa(selectedEntries[0].Age): namesWithAge(g.masterData, selectedEntries[0].Age),
+					strconv.Itoa(selectedEntries[1].Age): namesWithAge(g.masterData, selectedEntries[1].Age),
+				}
+			} else if config.IsCombinedRequest {
+				selectedEntries := g.randomSampleEntries(g.masterData, 3)
+				templateData["QueryName1"] = selectedEntries[0].Name
+				templateData["QueryName2"] = selectedEntries[1].Name
+				templateData["QueryAge3"] = g.dataFormat.age(selectedEntries[2].Age)
+				queriedNames = []string{selectedEntries[0].Name, selectedEntries[1].Name}
+				queriedAges = []int{selectedEntries[2].Age}
+				expected = map[string]interface{}{
+					"ages":           agesForNames([]string{selectedEntries[0].Name, selectedEntries[1].Name}, g.entryByName),
+					"names_with_age": namesWithAge(g.masterData, selectedEntries[2].Age),
+				}
+			} else if config.IsConfirmation {
+				if len(g.allNames) < config.QueryCount {
+					selectedNames = g.randomSampleNames(g.allNames, len(g.allNames))
+				}
+				queryItems = g.setQueryItems(templateData, selectedNames)
+				queriedNames = selectedNames
+				_, present := g.entryByName[nonExistentName]
+				expected = map[string]interface{}{
+					"ages":        agesForNames(selectedNames, g.entryByName),
+					"absent_name": nonExistentName,
+					"present":     present,
+				}
+			} else if config.IsSortedAges {
+				expected = sortedByAge(selectedNames, g.entryByName)
+			} else if config.IsSplitAttribute {
+				if len(g.masterData) <= 2*g.opts.JoinDistance {
+					log.Printf("Warning: -join-distance %d needs more than %d entries for %s, but there are %d. Skipping.", g.opts.JoinDistance, 2*g.opts.JoinDistance, config.Desc, len(g.masterData))
+					return
+				}
+				split := make(map[string]bool, len(selectedNames))
+				for _, name := range selectedNames {
+					split[name] = true
+				}
+				block, indexRows := g.formatSplitAttributeBlock(g.masterData, g.dataFormat, split, g.opts.JoinDistance)
+				promptDataBlock = g.wrapDataBlock(block, g.opts.DataHeader, g.opts.DataFooter)
+				rowGaps := make(map[string]int, len(selectedNames))
+				for _, name := range selectedNames {
+					if !strings.Contains(block, g.splitIndexLine(g.entryByName[name], g.dataFormat)) {
+						g.integrityFailures = append(g.integrityFailures, fmt.Sprintf("%s: index line for %q does not give age %d", config.Desc, name, g.entryByName[name].Age))
+						canGenerate = false
+					}
+					gap := indexRows[name] - g.indexByName[name]
+					if gap < 0 {
+						gap = -gap
+					}
+					rowGaps[name] = gap
+				}
+				expected = map[string]interface{}{
+					"ages":     agesForNames(selectedNames, g.entryByName),
+					"row_gaps": rowGaps,
+				}
+			} else if config.IsAgeUpdates {
+				updates := g.buildAgeUpdates(g.masterData, selectedNames, g.entryByName, g.opts.Updates)
+				currentAges := agesForNames(selectedNames, g.entryByName)
+				lines := make([]string, len(updates))
+				for i, update := range updates {
+					lines[i] = fmt.Sprintf("Update: %s's age is now %s", update.Name, g.dataFormat.age(update.Age))
+					if _, queried := currentAges[update.Name]; queried {
+						currentAges[update.Name] = update.Age
+					}
+				}
+				templateData["UpdateLog"] = strings.Join(lines, "\n")
+				expected = map[string]interface{}{
+					"current_ages":  currentAges,
+					"original_ages": agesForNames(selectedNames, g.entryByName),
+					"updates":       updates,
+				}
+			} else if config.IsMixedAges {
+				absentNames, err := g.generateAbsentNames(g.opts.AbsentNames, g.entryByName)
+				if err != nil {
+					log.Printf("Warning: %v for %s. Skipping.", err, config.Desc)
+					return
+				}
+				mixedNames := append(append([]string{}, selectedNames...), absentNames...)
+				g.rng.Shuffle(len(mixedNames), func(i, j int) { mixedNames[i], mixedNames[j] = mixedNames[j], mixedNames[i] })
+				queryItems = g.setQueryItems(templateData, mixedNames)
+				templateData["AbsentAnswer"] = ABSENT_AGE_ANSWER
+				answers := make(map[string]interface{}, len(mixedNames))
+				for _, name := range selectedNames {
+					answers[name] = g.entryByName[name].Age
+				}
+				for _, name := range absentNames {
+					answers[name] = ABSENT_AGE_ANSWER
+				}
+				expected = map[string]interface{}{
+					"answers": answers,
+					"ages":    agesForNames(selectedNames, g.entryByName),
+					"absent":  absentNames,
+				}
+				matchCount = len(mixedNames)
+			} else if config.IsMixedPresence {
+				absentNames, err := g.generateAbsentNames(g.opts.AbsentNames, g.entryByName)
+				if err != nil {
+					log.Printf("Warning: %v for %s. Skipping.", err, config.Desc)
+					return
+				}
+				mixedNames := append(append([]string{}, selectedNames...), absentNames...)
+				g.rng.Shuffle(len(mixedNames), func(i, j int) { mixedNames[i], mixedNames[j] = mixedNames[j], mixedNames[i] })
+				queryItems = g.setQueryItems(templateData, mixedNames)
+				expected = map[string][]string{
+					"present": selectedNames,
+					"absent":  absentNames,
+				}
+				matchCount = len(mixedNames)
+			}
+		}
+	} else if len(config.QueryIndices) > 0 {
+		realIdx1, realIdx2, ok := focusIndices(config.QueryIndices, len(g.masterData))
+		if !ok {
+			log.Printf("Warning: Invalid or duplicate query indices for %s (%d entries). Skipping.", config.Desc, len(g.masterData))
+			canGenerate = false
+		} else {
+			templateData["QueryName1"] = g.masterData[realIdx1].Name
+			templateData["QueryName2"] = g.masterData[realIdx2].Name
+			queriedNames = []string{g.masterData[realIdx1].Name, g.masterData[realIdx2].Name}
+			expected = agesForNames(queriedNames, g.entryByName)
+		}
+	} else if config.IsSequential {
+		if len(g.masterData) < 5 {
+			log.Printf("Warning: Not enough data (%d) for sequential query in %s. Skipping.", len(g.masterData), config.Desc)
+			canGenerate = false
+		} else {
+			startIndex := g.rng.Intn(len(g.masterData) - 4)
+			sequentialNames := make([]string, 5)
+			for i := 0; i < 5; i++ {
+				templateData[fmt.Sprintf("QueryName%d", i+1)] = g.masterData[startIndex+i].Name
+				sequentialNames[i] = g.masterData[startIndex+i].Name
+			}
+			expected = agesForNames(sequentialNames, g.entryByName)
+			queriedNames = sequentialNames
+		}
+	} else if config.IsMultiCity {
+		targetCity, matches, ok := g.pickFilterTarget(g.masterData, func() string { return g.pickKnownCity(g.masterData) },
+			func(e PersonEntry) string { return e.City }, g.opts.MinTargetMatches)
+		if !ok {
+			log.Printf("Warning: No city with at least %d resident(s) in %d draws for %s. Skipping.", g.opts.MinTargetMatches, TARGET_PICK_ATTEMPTS, config.Desc)
+			canGenerate = false
+		} else {
+			templateData["TargetCity"] = targetCity
+			expected = matches
+			matchCount = len(matches)
+		}
+	} else if config.IsMultiJob {
+		targetJobTitle, matches, ok := g.pickFilterTarget(g.masterData, func() string { return g.masterData[g.rng.Intn(len(g.masterData))].JobTitle },
+			func(e PersonEntry) string { return e.JobTitle }, g.opts.MinTargetMatches)
+		if !ok {
+			log.Printf("Warning: No job title with at least %d holder(s) in %d draws for %s. Skipping.", g.opts.MinTargetMatches, TARGET_PICK_ATTEMPTS, config.Desc)
+			canGenerate = false
+		} else {
+			templateData["TargetJobTitle"] = targetJobTitle
+			expected = matches
+			matchCount = len(matches)
+		}
+	} else if config.IsMultiAgeCity {
+		targetCity := g.pickKnownCity(g.masterData)
+		if targetCity == "" {
+			canGenerate = false
+		} else {
+			templateData["TargetCity"] = targetCity
+			midAge := g.masterData[g.rng.Intn(len(g.masterData))].Age
+			minAgeQuery := midAge - 5
+			maxAgeQuery := midAge + 5
+			if minAgeQuery < g.schema.NumberMin {
+				minAgeQuery = g.schema.NumberMin
+			}
+			if maxAgeQuery > g.schema.NumberMax {
+				maxAgeQuery = g.schema.NumberMax
+			}
+			if minAgeQuery > maxAgeQuery {
+				minAgeQuery = maxAgeQuery
+			}
+			templateData["MinAge"] = strconv.Itoa(minAgeQuery)
+			templateData["MaxAge"] = strconv.Itoa(maxAgeQuery)
+			matches := filterEntries(g.masterData, func(e PersonEntry) bool {
+				return e.City == targetCity && e.Age >= minAgeQuery && e.Age <= maxAgeQuery
+			})
+			matchNames := make([]string, len(matches))
+			for i, entry := range matches {
+				matchNames[i] = entry.Name
+			}
+			expected = matchNames
+			matchCount = len(matchNames)
+		}
+	} else if config.IsMultiCount {
+		targetCity := g.pickKnownCity(g.masterData)
+		if targetCity == "" {
+			canGenerate = false
+		} else {
+			targetJobTitle := g.masterData[g.rng.Intn(len(g.masterData))].JobTitle
+			templateData["TargetJobTitle"] = targetJobTitle
+			templateData["TargetCity"] = targetCity
+			count := len(filterEntries(g.masterData, func(e PersonEntry) bool {
+				return e.JobTitle == targetJobTitle && e.City == targetCity
+			}))
+			expected = count
+			matchCount = count
+		}
+	} else if config.IsZeroCount {
+		adjusted, pair, ok := g.withAbsentJobCity(g.masterData)
+		if !ok {
+			log.Printf("Warning: Cannot find or make a job title and city that never occur together for %s. Skipping.", config.Desc)
+			canGenerate = false
+		} else {
+			promptDataBlock = g.wrapDataBlock(g.formatDataBlock(adjusted, g.dataFormat), g.opts.DataHeader, g.opts.DataFooter)
+			templateData["TargetJobTitle"] = pair[0]
+			templateData["TargetCity"] = pair[1]
+			expected = 0
+			matchCount = 0
+		}
+	} else if config.IsCountDifference {
+		counts := jobTitleCounts(g.masterData)
+		titles := make([]string, 0, len(counts))
+		for title := range counts {
+			titles = append(titles, title)
+		}
+		sort.Strings(titles)
+		if len(titles) < 2 {
+			log.Printf("Warning: Fewer than two job titles in the data for %s. Skipping.", config.Desc)
+			canGenerate = false
+		} else {
+			// Either order is asked, so the sign varies; a pair with unequal counts is preferred.
+			var first, second string
+			for attempt := 0; attempt < 10 && (first == "" || counts[first] == counts[second]); attempt++ {
+				order := g.rng.Perm(len(titles))
+				first, second = titles[order[0]], titles[order[1]]
+			}
+			templateData["TargetJobTitle"] = first
+			templateData["TargetJobTitle2"] = second
+			expected = map[string]interface{}{
+				"job_title":         first,
+				"job_title_2":       second,
+				"first_count":       counts[first],
+				"second_count":      counts[second],
+				"signed_difference": counts[first] - counts[second],
+			}
+			matchCount = counts[first] + counts[second]
+		}
+	} else if config.IsHomonymCity {
+		adjusted, city, country, country2, ok := g.withHomonymCity(g.masterData)
+		if !ok {
+			log.Printf("Warning: Fewer than two countries among the cities for %s. Skipping.", config.Desc)
+			canGenerate = false
+		} else {
+			promptDataBlock = g.wrapDataBlock(g.formatDataBlock(adjusted, g.dataFormat), g.opts.DataHeader, g.opts.DataFooter)
+			countIn := func(country string) int {
+				return len(filterEntries(adjusted, func(e PersonEntry) bool { return e.City == city && e.Country == country }))
+			}
+			templateData["TargetCity"] = city
+			templateData["TargetCountry"] = country
+			templateData["TargetCountry2"] = country2
+			expected = map[string]interface{}{
+				"city":           city,
+				"first_country":  country,
+				"second_country": country2,
+				"first_count":    countIn(country),
+				"second_count":   countIn(country2),
+			}
+			matchCount = countIn(country) + countIn(country2)
+		}
+	} else if config.IsCityJobBreakdown {
+		targetCity, ok := g.pickBreakdownCity(g.masterData, MIN_BREAKDOWN_RESIDENTS)
+		if targetCity == "" {
+			canGenerate = false
+		} else {
+			if !ok {
+				log.Printf("Warning: No city has %d+ residents with varied job titles for %s. Using the largest city, %s.", MIN_BREAKDOWN_RESIDENTS, config.Desc, targetCity)
+			}
+			residents := filterEntries(g.masterData, func(e PersonEntry) bool { return e.City == targetCity })
+			templateData["TargetCity"] = targetCity
+			expected = map[string]interface{}{
+				"city":   targetCity,
+				"counts": jobTitleCounts(residents),
+			}
+			matchCount = len(residents)
+		}
+	} else if config.IsAgeComparison {
+		over, under := g.opts.OverAge, g.opts.UnderAge
+		if over == 0 && under == 0 {
+			over, under = g.pickAgeThresholds()
+		}
+		overCount := len(filterEntries(g.masterData, func(e PersonEntry) bool { return e.Age > over }))
+		underCount := len(filterEntries(g.masterData, func(e PersonEntry) bool { return e.Age < under }))
+		if overCount > 2*underCount || underCount > 2*overCount {
+			log.Printf("Warning: Thresholds over %d (%d people) and under %d (%d people) make %s one-sided.", over, overCount, under, underCount, config.Desc)
+		}
+		templateData["OverAge"] = over
+		templateData["UnderAge"] = under
+		expected = map[string]interface{}{
+			"over_age":    over,
+			"under_age":   under,
+			"over_count":  overCount,
+			"under_count": underCount,
+			"more_over":   overCount > underCount,
+		}
+		matchCount = overCount + underCount
+	} else if config.IsMostCommonCity || config.IsMostCommonJob {
+		counts := make(map[string]int)
+		for _, entry := range g.masterData {
+			value := entry.JobTitle
+			if config.IsMostCommonCity {
+				value = entry.City
+			}
+			if value != "" {
+				counts[value]++
+			}
+		}
+		mode, runnerUp, tied := modeOf(counts)
+		if mode == "" {
+			canGenerate = false
+		} else {
+			if tied {
+				log.Printf("Warning: %q and %q tie for the most common value in %s; the key uses the alphabetically first. Set -mode-skew for a clear answer.", mode, runnerUp, config.Desc)
+			}
+			expected = map[string]interface{}{
+				"mode":            mode,
+				"count":           counts[mode],
+				"runner_up":       runnerUp,
+				"runner_up_count": counts[runnerUp],
+				"tied":            tied,
+			}
+			matchCount = counts[mode]
+		}
+	} else if config.IsDistinctCities {
+		// Prefer a job held by at least MIN_BREAKDOWN_RESIDENTS people spread over several cities.
+		candidates := []string{}
+		for _, job := range g.schema.Categories {
+			holders := filterEntries(g.masterData, func(e PersonEntry) bool { return e.JobTitle == job })
+			if len(holders) >= MIN_BREAKDOWN_RESIDENTS && len(distinctCities(holders)) >= 3 {
+				candidates = append(candidates, job)
+			}
+		}
+		if len(candidates) == 0 {
+			log.Printf("Warning: No job title has %d+ holders in 3+ cities for %s. Skipping.", MIN_BREAKDOWN_RESIDENTS, config.Desc)
+			canGenerate = false
+		} else {
+			targetJobTitle := candidates[g.rng.Intn(len(candidates))]
+			holders := filterEntries(g.masterData, func(e PersonEntry) bool { return e.JobTitle == targetJobTitle })
+			cities := distinctCities(holders)
+			templateData["TargetJobTitle"] = targetJobTitle
+			expected = map[string]interface{}{
+				"job_title":       targetJobTitle,
+				"distinct_cities": len(cities),
+				"cities":          cities,
+			}
+			matchCount = len(holders)
+		}
+	} else if config.IsSumAges {
+		if len(g.masterData) < 3 {
+			log.Printf("Warning: Not enough data (%d) for sum query in %s (needs 3). Skipping.", len(g.masterData), config.Desc)
+			canGenerate = false
+		} else {
+			selectedEntries := g.randomSampleEntries(g.masterData, 3)
+			names := make([]string, len(selectedEntries))
+			sum := 0
+			for i, entry := range selectedEntries {
+				templateData[fmt.Sprintf("QueryName%d", i+1)] = entry.Name
+				names[i] = entry.Name
+				sum += entry.Age
+			}
+			expected = map[string]interface{}{
+				"ages": agesForNames(names, g.entryByName),
+				"sum":  sum,
+			}
+			queriedNames = names
+		}
+	} else if config.IsAgeDifference {
+		if len(g.masterData) < 2 {
+			log.Printf("Warning: Not enough data (%d) for difference query in %s (needs 2). Skipping.", len(g.masterData), config.Desc)
+			canGenerate = false
+		} else {
+			selectedEntries := g.randomSampleEntries(g.masterData, 2)
+			older, younger := selectedEntries[0], selectedEntries[1]
+			if younger.Age > older.Age {
+				older, younger = younger, older
+			}
+			templateData["QueryName1"] = older.Name
+			templateData["QueryName2"] = younger.Name
+			queriedNames = []string{older.Name, younger.Name}
+			expected = map[string]interface{}{
+				"ages":       agesForNames([]string{older.Name, younger.Name}, g.entryByName),
+				"difference": older.Age - younger.Age,
+			}
+		}
+	} else if config.IsYearsUntilAge {
+		younger := filterEntries(g.masterData, func(e PersonEntry) bool { return e.Age < g.opts.MilestoneAge })
+		if len(younger) == 0 {
+			log.Printf("Warning: Nobody is younger than -milestone-age %d for %s. Skipping.", g.opts.MilestoneAge, config.Desc)
+			canGenerate = false
+		} else {
+			entry := younger[g.rng.Intn(len(younger))]
+			years := g.opts.MilestoneAge - entry.Age
+			if years < 0 {
+				years = 0
+			}
+			templateData["QueryName1"] = entry.Name
+			templateData["MilestoneAge"] = g.opts.MilestoneAge
+			queriedNames = []string{entry.Name}
+			expected = map[string]interface{}{
+				"person":      entry.Name,
+				"age":         entry.Age,
+				"target_age":  g.opts.MilestoneAge,
+				"years_until": years,
+			}
+		}
+	} else if config.IsJobSubstring {
+		keywords := make([]string, 0, len(jobTitleKeywords))
+		for _, keyword := range jobTitleKeywords {
+			if len(filterEntries(g.masterData, func(e PersonEntry) bool { return strings.Contains(e.JobTitle, keyword) })) > 0 {
+				keywords = append(keywords, keyword)
+			}
+		}
+		if len(keywords) == 0 {
+			log.Printf("Warning: No job title keyword matches any entry for %s. Skipping.", config.Desc)
+			canGenerate = false
+		} else {
+			keyword := keywords[g.rng.Intn(len(keywords))]
+			templateData["TargetKeyword"] = keyword
+			matches := filterEntries(g.masterData, func(e PersonEntry) bool { return strings.Contains(e.JobTitle, keyword) })
+			expected = matches
+			matchCount = len(matches)
+		}
+	} else if config.IsAbsentField {
+		if len(g.masterData) == 0 {
+			canGenerate = false
+		} else {
+			name := g.masterData[g.rng.Intn(len(g.masterData))].Name
+			templateData["QueryName1"] = name
+			queriedNames = []string{name}
+			expected = map[string]interface{}{
+				"name":   name,
+				"answer": NOT_AVAILABLE_ANSWER,
+			}
+		}
+	} else if config.IsCompositeKey {
+		targetJobTitle, targetCity, unique, ok := g.pickCompositeKey(g.masterData)
+		if !ok {
+			canGenerate = false
+		} else {
+			if !unique {
+				log.Printf("Warning: No (job title, city) pair identifies a single person for %s. Asking for all matches instead.", config.Desc)
+			}
+			matches := filterEntries(g.masterData, func(e PersonEntry) bool {
+				return e.JobTitle == targetJobTitle && e.City == targetCity
+			})
+			templateData["TargetJobTitle"] = targetJobTitle
+			templateData["TargetCity"] = targetCity
+			templateData["Unique"] = unique
+			expected = map[string]interface{}{
+				"unique":  unique,
+				"matches": matches,
+			}
+			matchCount = len(matches)
+		}
+	} else if config.IsUniqueTriple {
+		entry, ok := g.pickUniqueTriple(g.masterData)
+		if !ok {
+			log.Printf("Warning: Every (age, city, job title) triple is shared by two or more people for %s. Skipping.", config.Desc)
+			canGenerate = false
+		} else {
+			templateData["TargetAge"] = g.dataFormat.age(entry.Age)
+			templateData["TargetCity"] = entry.City
+			templateData["TargetJobTitle"] = entry.JobTitle
+			expected = map[string]interface{}{
+				"name": entry.Name,
+				"constraints": map[string]interface{}{
+					"age":       entry.Age,
+					"city":      entry.City,
+					"job_title": entry.JobTitle,
+				},
+			}
+			queriedNames = []string{entry.Name}
+		}
+	} else if config.IsMarkdownTable {
+		targetJobTitle, minAgeQuery, maxAgeQuery, ok := g.pickTableFilter(g.masterData)
+		if !ok {
+			log.Printf("Warning: No job title has 2-%d holders within a %d-year age band for %s. Skipping.", MARKDOWN_TABLE_MAX_ROWS, MARKDOWN_TABLE_AGE_SPAN, config.Desc)
+			canGenerate = false
+		} else {
+			matches := filterEntries(g.masterData, func(e PersonEntry) bool {
+				return e.JobTitle == targetJobTitle && e.Age >= minAgeQuery && e.Age <= maxAgeQuery
+			})
+			templateData["TargetJobTitle"] = targetJobTitle
+			templateData["MinAge"] = strconv.Itoa(minAgeQuery)
+			templateData["MaxAge"] = strconv.Itoa(maxAgeQuery)
+			expected = matches
+			matchCount = len(matches)
+			for _, entry := range matches {
+				queriedNames = append(queriedNames, entry.Name)
+			}
+		}
+	} else if config.IsNestedJSON {
+		withCity := filterEntries(g.masterData, func(e PersonEntry) bool { return e.City != "" })
+		block, err := formatNestedByCity(g.masterData)
+		if err != nil || len(withCity) == 0 {
+			log.Printf("Warning: Cannot build a nested JSON block with a city for %s (%v). Skipping.", config.Desc, err)
+			canGenerate = false
+		} else {
+			entry := withCity[g.rng.Intn(len(withCity))]
+			quoted, _ := json.Marshal(entry.Name)
+			if !strings.Contains(block, `"name": `+string(quoted)) {
+				g.integrityFailures = append(g.integrityFailures, fmt.Sprintf("%s: queried name %q not found in nested block", config.Desc, entry.Name))
+				canGenerate = false
+			}
+			promptDataBlock = g.wrapDataBlock(block, g.opts.DataHeader, g.opts.DataFooter)
+			templateData["QueryName1"] = entry.Name
+			expected = map[string]interface{}{
+				"entry": entry,
+				"city":  entry.City,
+			}
+			matchCount = 1
+		}
+	} else if config.IsSingletonCity {
+		adjusted, ok := g.withSingletonCity(g.masterData)
+		if !ok {
+			log.Printf("Warning: Fewer than two known cities for %s. Skipping.", config.Desc)
+			canGenerate = false
+		} else {
+			promptDataBlock = g.wrapDataBlock(g.formatDataBlock(adjusted, g.dataFormat), g.opts.DataHeader, g.opts.DataFooter)
+			cities := singletonCities(adjusted)
+			residents := make(map[string]string, len(cities))
+			for _, entry := range adjusted {
+				for _, city := range cities {
+					if entry.City == city {
+						residents[city] = entry.Name
+						queriedNames = append(queriedNames, entry.Name)
+					}
+				}
+			}
+			// Any one singleton is a correct answer, so the key accepts each of them.
+			expected = map[string]interface{}{
+				"accepted_cities": cities,
+				"residents":       residents,
+			}
+			matchCount = 1
+		}
+	} else if config.IsListOrder {
+		targetJobTitle, minAgeQuery, maxAgeQuery, ok := g.pickTableFilter(g.masterData)
+		if !ok {
+			log.Printf("Warning: No job title has 2-%d holders within a %d-year age band for %s. Skipping.", MARKDOWN_TABLE_MAX_ROWS, MARKDOWN_TABLE_AGE_SPAN, config.Desc)
+			canGenerate = false
+		} else {
+			// filterEntries keeps masterData order, which is the order the rows are shown in.
+			matches := filterEntries(g.masterData, func(e PersonEntry) bool {
+				return e.JobTitle == targetJobTitle && e.Age >= minAgeQuery && e.Age <= maxAgeQuery
+			})
+			templateData["TargetJobTitle"] = targetJobTitle
+			templateData["MinAge"] = strconv.Itoa(minAgeQuery)
+			templateData["MaxAge"] = strconv.Itoa(maxAgeQuery)
+			for _, entry := range matches {
+				queriedNames = append(queriedNames, entry.Name)
+			}
+			expected = map[string]interface{}{
+				"ordered_names": queriedNames,
+				"job_title":     targetJobTitle,
+				"min_age":       minAgeQuery,
+				"max_age":       maxAgeQuery,
+			}
+			matchCount = len(matches)
+		}
+	} else if config.IsCountryLookup {
+		// With -country-mode scrambled, prefer someone whose recorded country is not the real one.
+		withCountry := filterEntries(g.masterData, func(e PersonEntry) bool { return e.City != "" && e.Country != "" })
+		if mismatched := filterEntries(withCountry, func(e PersonEntry) bool { return e.Country != g.countries[e.City] }); len(mismatched) > 0 {
+			withCountry = mismatched
+		}
+		if len(withCountry) == 0 {
+			log.Printf("Warning: No entries with both a city and a country for %s. Skipping.", config.Desc)
+			canGenerate = false
+		} else {
+			entry := withCountry[g.rng.Intn(len(withCountry))]
+			templateData["QueryName1"] = entry.Name
+			queriedNames = []string{entry.Name}
+			expected = map[string]interface{}{
+				"city":         entry.City,
+				"country":      entry.Country,
+				"real_country": g.countries[entry.City],
+				"country_mode": g.opts.CountryMode,
+			}
+			matchCount = 1
+		}
+	} else if config.IsDuplicateCheck {
+		if len(g.masterData) < DUPLICATE_ENTRY_COUNT {
+			log.Printf("Warning: Not enough data (%d) for duplicate check in %s (needs %d). Skipping.", len(g.masterData), config.Desc, DUPLICATE_ENTRY_COUNT)
+			canGenerate = false
+		} else {
+			withDuplicates, duplicateNames := g.injectDuplicateEntries(g.masterData, DUPLICATE_ENTRY_COUNT)
+			promptDataBlock = g.wrapDataBlock(g.formatDataBlock(withDuplicates, g.dataFormat), g.opts.DataHeader, g.opts.DataFooter)
+			expected = duplicateNames
+			queriedNames = duplicateNames
+		}
+	} else if config.IsTwoSection {
+		if len(g.masterData) < 2*(TWO_SECTION_SHARED+TWO_SECTION_UNIQUE) {
+			log.Printf("Warning: Not enough data (%d) for two-section query in %s (needs %d). Skipping.", len(g.masterData), config.Desc, 2*(TWO_SECTION_SHARED+TWO_SECTION_UNIQUE))
+			canGenerate = false
+		} else if customers, sharedNames, err := g.buildSecondSection(g.masterData, len(g.masterData)/2, TWO_SECTION_SHARED, g.fetchedCities); err != nil {
+			log.Printf("Warning: Cannot build second section for %s: %v. Skipping.", config.Desc, err)
+			canGenerate = false
+		} else {
+			sectionNames := []string{"Employees", "Customers"}
+			sections := [][]PersonEntry{g.masterData, customers}
+			target := g.rng.Intn(len(sections))
+			other := 1 - target
+
+			sharedSet := make(map[string]bool, len(sharedNames))
+			for _, name := range sharedNames {
+				sharedSet[name] = true
+			}
+			targetOnly := filterEntries(sections[target], func(e PersonEntry) bool { return !sharedSet[e.Name] })
+			names := append([]string{}, sharedNames...)
+			for _, entry := range g.randomSampleEntries(targetOnly, TWO_SECTION_UNIQUE) {
+				names = append(names, entry.Name)
+			}
+			g.rng.Shuffle(len(names), func(i, j int) { names[i], names[j] = names[j], names[i] })
+
+			byName := func(entries []PersonEntry) map[string]PersonEntry {
+				m := make(map[string]PersonEntry, len(entries))
+				for _, entry := range entries {
+					m[entry.Name] = entry
+				}
+				return m
+			}
+			targetByName, otherByName := byName(sections[target]), byName(sections[other])
+			otherAges := make(map[string]int, len(sharedNames))
+			for _, name := range sharedNames {
+				otherAges[name] = otherByName[name].Age
+			}
+
+			promptDataBlock = g.wrapDataBlock(fmt.Sprintf("%s:\n%s\n\n%s:\n%s",
+				sectionNames[0], g.formatDataBlock(sections[0], g.dataFormat),
+				sectionNames[1], g.formatDataBlock(sections[1], g.dataFormat)), g.opts.DataHeader, g.opts.DataFooter)
+			templateData["TargetSection"] = sectionNames[target]
+			queryItems = g.setQueryItems(templateData, names)
+			expected = map[string]interface{}{
+				"section":            sectionNames[target],
+				"ages":               agesForNames(names, targetByName),
+				"other_section_ages": otherAges,
+			}
+			queriedNames = names
+
+			offset := 0
+			if target == 1 {
+				offset = len(g.masterData)
+			}
+			positionIndex = make(map[string]int, len(sections[target]))
+			for i, entry := range sections[target] {
+				positionIndex[entry.Name] = offset + i
+			}
+			positionTotal = len(g.masterData) + len(customers)
+		}
+	} else if config.IsCorruptedLookup {
+		corruptedNames := []string{}
+		corruptionsByName := make(map[string][]Corruption)
+		for _, corruption := range g.corruptions {
+			if len(corruptionsByName[corruption.Name]) == 0 {
+				corruptedNames = append(corruptedNames, corruption.Name)
+			}
+			corruptionsByName[corruption.Name] = append(corruptionsByName[corruption.Name], corruption)
+		}
+		if len(corruptedNames) == 0 {
+			log.Printf("Warning: No corrupted entries for %s (set -corruption-rate). Skipping.", config.Desc)
+			canGenerate = false
+		} else {
+			names := g.randomSampleNames(corruptedNames, CORRUPTED_QUERY_COUNT)
+			answers := make(map[string]map[string]interface{}, len(names))
+			queried := []Corruption{}
+			for _, name := range names {
+				entry := g.entryByName[name]
+				answers[name] = map[string]interface{}{"age": entry.Age, "city": entry.City}
+				queried = append(queried, corruptionsByName[name]...)
+			}
+			queryItems = g.setQueryItems(templateData, names)
+			expected = map[string]interface{}{
+				"answers":     answers,
+				"corruptions": queried,
+			}
+			queriedNames = names
+		}
+	} else if config.IsBornInYear {
+		withYears := filterEntries(g.masterData, func(e PersonEntry) bool { return e.BirthYear != nil })
+		if g.opts.ReferenceYear == 0 || len(withYears) == 0 {
+			log.Printf("Warning: No birth years for %s (set -reference-year). Skipping.", config.Desc)
+			canGenerate = false
+		} else {
+			targetYear := *withYears[g.rng.Intn(len(withYears))].BirthYear
+			templateData["TargetBirthYear"] = targetYear
+			matchNames := []string{}
+			for _, entry := range filterEntries(g.masterData, func(e PersonEntry) bool { return e.BirthYear != nil && *e.BirthYear == targetYear }) {
+				matchNames = append(matchNames, entry.Name)
+			}
+			expected = map[string]interface{}{
+				"birth_year": targetYear,
+				"age":        g.opts.ReferenceYear - targetYear,
+				"names":      matchNames,
+			}
+			matchCount = len(matchNames)
+		}
+	} else if config.IsHardReverse {
+		targetAge, ok := g.pickConfusableAge(g.masterData)
+		if !ok {
+			log.Printf("Warning: No age is shared by two or more people for %s. Skipping.", config.Desc)
+			canGenerate = false
+		} else {
+			names := namesWithAge(g.masterData, targetAge)
+			sort.Strings(names)
+			templateData["QueryAge1"] = g.dataFormat.age(targetAge)
+			expected = map[string]interface{}{
+				"age":   targetAge,
+				"count": len(names),
+				"names": names,
+			}
+			queriedAges = []int{targetAge}
+			matchCount = len(names)
+		}
+	} else if config.IsLineLookup {
+		if !g.dataFormat.LineNumbers || len(g.masterData) == 0 {
+			log.Printf("Warning: No line numbers in the data block for %s (set -line-numbers). Skipping.", config.Desc)
+			canGenerate = false
+		} else {
+			row := g.rng.Intn(len(g.masterData))
+			entry := g.masterData[row]
+			// The line is read back from the block, since -shuffle-fields and -format-noise make
+			// a fresh rendering of the row differ from the one written.
+			line := ""
+			if start := strings.Index(promptDataBlock, linePrefix(row, len(g.masterData))); start >= 0 {
+				line, _, _ = strings.Cut(promptDataBlock[start:], g.dataFormat.rowSeparator())
+			}
+			if nameField := g.dataFormat.field(g.dataFormat.label(g.schema.NameLabel), entry.Name); !strings.Contains(line, nameField) {
+				g.integrityFailures = append(g.integrityFailures, fmt.Sprintf("%s: line %d does not list %q", config.Desc, row+1, nameField))
+				return
+			}
+			templateData["LineNumber"] = row + 1
+			expected = map[string]interface{}{
+				"line":  row + 1,
+				"entry": entry,
+			}
+			queriedNames = []string{entry.Name}
+		}
+	} else if config.IsIndirectRef {
+		jobTitle, oldest, referent, ok := g.pickIndirectReferent(g.masterData)
+		if !ok {
+			log.Printf("Warning: No job title has a unique oldest or youngest holder for %s. Skipping.", config.Desc)
+			canGenerate = false
+		} else {
+			superlative := "youngest"
+			if oldest {
+				superlative = "oldest"
+			}
+			templateData["TargetJobTitle"] = jobTitle
+			templateData["Oldest"] = oldest
+			expected = map[string]interface{}{
+				"superlative": superlative,
+				"job_title":   jobTitle,
+				"name":        referent.Name,
+				"age":         referent.Age,
+				"city":        referent.City,
+			}
+			queriedNames = []string{referent.Name}
+		}
+	} else if config.IsTopNWithTies {
+		jobTitle, tied, ok := g.pickTopNTieJob(g.masterData, TOP_N_OLDEST)
+		if !ok {
+			log.Printf("Warning: No job title has more than %d holders for %s. Skipping.", TOP_N_OLDEST, config.Desc)
+			canGenerate = false
+		} else {
+			if !tied {
+				log.Printf("Warning: No job title has a tie at rank %d for %s; the tie rule will not change the answer.", TOP_N_OLDEST, config.Desc)
+			}
+			holders := filterEntries(g.masterData, func(e PersonEntry) bool { return e.JobTitle == jobTitle })
+			ranking := topNWithTies(holders, TOP_N_OLDEST)
+			names := make([]string, len(ranking))
+			for i, item := range ranking {
+				names[i] = item.Name
+			}
+			templateData["TargetJobTitle"] = jobTitle
+			templateData["TopN"] = TOP_N_OLDEST
+			expected = map[string]interface{}{
+				"job_title":       jobTitle,
+				"top_n":           TOP_N_OLDEST,
+				"cutoff_age":      ranking[TOP_N_OLDEST-1].Age,
+				"tie_at_boundary": len(ranking) > TOP_N_OLDEST,
+				"ranking":         ranking,
+				"ages":            agesForNames(names, g.entryByName),
+			}
+			queriedNames = names
+		}
+	} else if config.IsAgeDescriptor {
+		if len(ageDescriptors) == 0 {
+			log.Printf("Warning: The age descriptor table is empty for %s. Skipping.", config.Desc)
+			canGenerate = false
+		} else {
+			descriptor := ageDescriptors[g.rng.Intn(len(ageDescriptors))]
+			inBand := func(e PersonEntry) bool { return e.Age >= descriptor.MinAge && e.Age <= descriptor.MaxAge }
+			candidates := []string{}
+			for _, job := range g.schema.Categories {
+				if len(filterEntries(g.masterData, func(e PersonEntry) bool { return e.JobTitle == job && inBand(e) })) > 0 {
+					candidates = append(candidates, job)
+				}
+			}
+			if len(candidates) == 0 {
+				log.Printf("Warning: Nobody is %s (%d-%d) for %s. Skipping.", descriptor.Labels["en"], descriptor.MinAge, descriptor.MaxAge, config.Desc)
+				canGenerate = false
+			} else {
+				targetJobTitle := candidates[g.rng.Intn(len(candidates))]
+				matches := filterEntries(g.masterData, func(e PersonEntry) bool { return e.JobTitle == targetJobTitle && inBand(e) })
+				matchNames := make([]string, len(matches))
+				for i, entry := range matches {
+					matchNames[i] = entry.Name
+				}
+				templateData["TargetJobTitle"] = targetJobTitle
+				templateData["AgeDescriptor"] = descriptor.Labels
+				templateData["MinAge"] = strconv.Itoa(descriptor.MinAge)
+				templateData["MaxAge"] = strconv.Itoa(descriptor.MaxAge)
+				expected = map[string]interface{}{
+					"descriptor": descriptor.Labels["en"],
+					"min_age":    descriptor.MinAge,
+					"max_age":    descriptor.MaxAge,
+					"job_title":  targetJobTitle,
+					"names":      matchNames,
+					"ages":       agesForNames(matchNames, g.entryByName),
+				}
+				queriedNames = matchNames
+			}
+		}
+	} else if config.IsJoinTable {
+		table := g.buildRegionTable(g.masterData)
+		if len(table) < 2 {
+			log.Printf("Warning: Need at least 2 known cities for the region table in %s (have %d). Skipping.", config.Desc, len(table))
+			canGenerate = false
+		} else {
+			counts := make(map[string]int)
+			for _, entry := range g.masterData {
+				if region, ok := table[entry.City]; ok {
+					counts[region]++
+				}
+			}
+			mode, runnerUp, tied := modeOf(counts)
+			if tied {
+				log.Printf("Warning: %q and %q tie for the region with the most people in %s; the key uses the alphabetically first.", mode, runnerUp, config.Desc)
+			}
+			templateData["RegionTable"] = g.formatRegionTable(table, g.dataFormat)
+			expected = map[string]interface{}{
+				"region":          mode,
+				"count":           counts[mode],
+				"runner_up":       runnerUp,
+				"runner_up_count": counts[runnerUp],
+				"tied":            tied,
+				"region_counts":   counts,
+				"city_regions":    table,
+			}
+			matchCount = counts[mode]
+		}
+	} else if config.IsMixedScript {
+		table := g.nativeCityTable(g.masterData)
+		targetJobTitle, targetCity, ok := g.pickMixedScriptTarget(g.masterData, table)
+		if !ok {
+			log.Printf("Warning: No job title has 2+ holders in a city with a native spelling for %s (%d fetched cities are in the fixture). Skipping.", config.Desc, len(table))
+			canGenerate = false
+		} else {
+			matches := filterEntries(g.masterData, func(e PersonEntry) bool {
+				return e.JobTitle == targetJobTitle && e.City == targetCity
+			})
+			// One match in each script, so neither spelling alone finds everyone.
+			localized, renamed := g.localizeCities(g.masterData, table, matches[0].Name, matches[1].Name)
+			promptDataBlock = g.wrapDataBlock(g.formatDataBlock(localized, g.dataFormat), g.opts.DataHeader, g.opts.DataFooter)
+			askedAs := targetCity
+			if g.rng.Intn(2) == 0 {
+				askedAs = table[targetCity]
+			}
+			renamedSet := make(map[string]bool, len(renamed))
+			for _, name := range renamed {
+				renamedSet[name] = true
+			}
+			matchNames := make([]string, len(matches))
+			nativeMatches := []string{}
+			for i, entry := range matches {
+				matchNames[i] = entry.Name
+				if renamedSet[entry.Name] {
+					nativeMatches = append(nativeMatches, entry.Name)
+				}
+			}
+			templateData["TargetJobTitle"] = targetJobTitle
+			templateData["TargetCity"] = askedAs
+			expected = map[string]interface{}{
+				"job_title":     targetJobTitle,
+				"target_city":   targetCity,
+				"native_city":   table[targetCity],
+				"asked_as":      askedAs,
+				"names":         matchNames,
+				"native_rows":   nativeMatches,
+				"renamed_count": len(renamed),
+			}
+			queriedNames = matchNames
+		}
+	} else if config.IsUnknownCity {
+		blankEntries := filterEntries(g.masterData, func(e PersonEntry) bool { return e.City == "" })
+		if len(blankEntries) == 0 {
+			log.Printf("Warning: No entries with an unknown city for %s (set -blank-city-rate). Skipping.", config.Desc)
+			canGenerate = false
+		} else {
+			blankNames := make([]string, len(blankEntries))
+			for i, entry := range blankEntries {
+				blankNames[i] = entry.Name
+			}
+			expected = blankNames
+			matchCount = len(blankNames)
+		}
+	}
+	// END POPULATE BLOCK
+	shownData := g.masterData
+	if canGenerate && g.opts.VisibleRows > 0 && !dependsOnWholeList(config) {
+		subset, err := g.visibleSubset(g.masterData, queriedNames, g.opts.VisibleRows)
+		if err != nil {
+			log.Printf("Warning: Cannot apply -visible-rows to %s: %v. Skipping.", config.Desc, err)
+			canGenerate = false
+		} else {
+			shownData = subset
+			promptDataBlock = g.wrapDataBlock(g.formatDataBlock(shownData, g.dataFormat), g.opts.DataHeader, g.opts.DataFooter)
+			positionIndex = make(map[string]int, len(shownData))
+			for i, entry := range shownData {
+				positionIndex[entry.Name] = i
+			}
+			positionTotal = len(shownData)
+		}
+	}
+	if canGenerate && g.opts.TargetTokens > 0 {
+		if dependsOnWholeList(config) || promptDataBlock != g.dataBlockString {
+			log.Printf("Warning: %s is answered over every row or renders its own data block; -target-tokens leaves it at its natural size.", config.Desc)
+		} else {
+			// The rest of the prompt is sized once with an empty data block; estimates are additive.
+			probeData := make(map[string]interface{}, len(templateData)+1)
+			for key, value := range templateData {
+				probeData[key] = value
+			}
+			probeData["DataBlock"] = ""
+			var probe bytes.Buffer
+			overhead, err := renderPrompt(&probe, config.Desc, g.schema.reword(config.Template), probeData, g.promptPrefix+g.preamble, g.markerInstruction)
+			if err != nil {
+				log.Printf("Error sizing %s for -target-tokens: %v", config.Desc, err)
+				canGenerate = false
+			} else {
+				overheadTokens := overhead.TokenEstimate
+				if g.opts.TokenizerCmd != "" {
+					overheadTokens = g.countTokens(probe.String())
+				}
+				if g.opts.OutputStyle == "chat" {
+					overheadTokens += g.countTokens(g.systemPromptText)
+				}
+				rows, block, fits := g.fitRowsToTokens(g.masterData, queriedNames, g.dataFormat, g.opts.TargetTokens-overheadTokens)
+				if !fits {
+					log.Printf("Warning: The queried rows of %s alone exceed -target-tokens %d; writing them without other rows.", config.Desc, g.opts.TargetTokens)
+				}
+				shownData = rows
+				promptDataBlock = block
+				sizedToTarget = true
+				positionIndex = make(map[string]int, len(shownData))
+				for i, entry := range shownData {
+					positionIndex[entry.Name] = i
+				}
+				positionTotal = len(shownData)
+				// Every row is shown and the prompt is still short: make up the rest with filler.
+				if missing := g.opts.TargetTokens - overheadTokens - g.countTokens(block); missing > 0 && len(rows) == len(g.masterData) {
+					configPreamble = g.buildPreamble(estimateTokens(g.preamble) + missing)
+				}
+			}
+		}
+	}
+	if canGenerate && config.IsVaryingDelimiter {
+		formats, err := g.varyingDelimiterFormats(g.dataFormat)
+		if err != nil {
+			log.Printf("Warning: Cannot vary delimiters for %s: %v. Skipping.", config.Desc, err)
+			canGenerate = false
+		} else {
+			promptDataBlock = g.wrapDataBlock(g.formatVaryingDelimiterBlock(shownData, formats), g.opts.DataHeader, g.opts.DataFooter)
+			blockFormats = formats
+		}
+	}
+	if canGenerate && config.IsDecoyInstruction {
+		block, decoyLine := g.insertDecoyLine(g.formatDataBlock(shownData, g.dataFormat), g.opts.DecoyInstruction, g.dataFormat.rowSeparator())
+		promptDataBlock = g.wrapDataBlock(block, g.opts.DataHeader, g.opts.DataFooter)
+		expected = map[string]interface{}{
+			"ages":       expected,
+			"decoy":      g.opts.DecoyInstruction,
+			"decoy_line": decoyLine,
+		}
+	}
+	templateData["DataBlock"] = promptDataBlock
+	if matchCount < 0 {
+		matchCount = len(queriedNames) + len(queriedAges)
+	}
+
+	if !canGenerate {
+		return
+	}
+	if problems := g.verifyQueriedEntries(promptDataBlock, blockFormats, queriedNames, queriedAges); len(problems) > 0 {
+		for _, problem := range problems {
+			g.integrityFailures = append(g.integrityFailures, fmt.Sprintf("%s: %s", config.Desc, problem))
+		}
+		return
+	}
+
+	// With -reorder-queries, list prompts also get a copy whose queried names are shuffled.
+	var reorderedData map[string]interface{}
+	if g.opts.ReorderQueries && len(queryItems) > 1 {
+		reorderedData = make(map[string]interface{}, len(templateData))
+		for key, value := range templateData {
+			reorderedData[key] = value
+		}
+		g.setQueryItems(reorderedData, g.reorderedQueryItems(queryItems))
+	}
+
+	// The English template is always written; each -question-langs entry adds a suffixed variant.
+	type promptVariant struct {
+		lang      string
+		reordered bool
+	}
+	variants := []promptVariant{}
+	for _, lang := range append([]string{""}, g.langs...) {
+		variants = append(variants, promptVariant{lang: lang})
+		if reorderedData != nil {
+			variants = append(variants, promptVariant{lang: lang, reordered: true})
+		}
+	}
+	for _, variant := range variants {
+		lang := variant.lang
+		name := sanitizeDesc(config.Desc)
+		templateText := g.schema.reword(config.Template)
+		if lang != "" {
+			name += "_" + lang
+			templateText = promptTranslations[lang][config.Desc]
+		}
+		if g.opts.StrictFormat {
+			templateText += g.strictFormatSuffix(config, lang)
+		}
+		variantData := templateData
+		if variant.reordered {
+			name += "_reordered"
+			variantData = reorderedData
+		}
+		// With -questions-per-file, English questions over the unmodified data block are held
+		// back for the bundles; anything with its own block or output format, or whose instructions
+		// lead the data block, is still written alone.
+		if g.opts.QuestionsPerFile > 0 && lang == "" && !variant.reordered && promptDataBlock == g.dataBlockString && !config.IsJSONOutput && !config.IsMarkdownTable && !config.IsSplitInstruction {
+			var question bytes.Buffer
+			if _, err := renderPrompt(&question, config.Desc, baselineTemplate(templateText), variantData, "", ""); err != nil {
+				log.Printf("Error rendering the %s question for a bundle: %v", config.Desc, err)
+				continue
+			}
+			g.bundled = append(g.bundled, bundledQuestion{Desc: config.Desc, Text: strings.TrimSpace(question.String()), Expected: expected, MatchCount: matchCount})
+			continue
+		}
+		filename := "prompt_" + name + g.promptExt
+		baselineTarget := filepath.Join(g.outputDir, "prompt_"+name+".baseline"+g.promptExt)
+		filepath := filepath.Join(g.outputDir, filename)
+
+		var buf bytes.Buffer
+		rendered, err := renderPrompt(&buf, config.Desc, templateText, variantData, g.promptPrefix+configPreamble, g.markerInstruction)
+		if err != nil {
+			log.Printf("Error rendering %s: %v", filename, err)
+			continue
+		}
+		if g.opts.TokenizerCmd != "" {
+			rendered.TokenEstimate = g.countTokens(buf.String())
+		}
+		annotation := ""
+		if g.opts.Annotate {
+			if annotation, err = answerAnnotation(expected); err != nil {
+				log.Printf("Error annotating %s: %v", filename, err)
+				continue
+			}
+		}
+		buf.WriteString(annotation)
+		content := buf.Bytes()
+		if g.opts.OutputStyle == "chat" {
+			content, err = encodeChatPrompt(g.systemPromptText, buf.String())
+			if err != nil {
+				log.Printf("Error encoding chat messages for %s: %v", filename, err)
+				continue
+			}
+			rendered.TokenEstimate += g.countTokens(g.systemPromptText)
+		}
+		if sizedToTarget && math.Abs(float64(rendered.TokenEstimate-g.opts.TargetTokens)) > g.opts.TargetTolerance*float64(g.opts.TargetTokens) {
+			log.Printf("Warning: %s is ~%d tokens, outside -target-tolerance %v of -target-tokens %d.", filename, rendered.TokenEstimate, g.opts.TargetTolerance, g.opts.TargetTokens)
+		}
+		// A non-empty file from an earlier, interrupted run is kept unless -force is set. Its answer
+		// comes from the earlier key or, when the key does not cover it (the run stopped before
+		// writing one), from this run if the file holds exactly what this run renders. Any other
+		// file, or one found with no earlier run loaded, is rewritten.
+		writtenPath, skipped := g.existingOutputFile(filepath)
+		if skipped && g.earlier == nil {
+			skipped = false
+		} else if skipped {
+			if _, ok := g.earlier.answers[filename]; !ok && !g.matchesOutputFile(writtenPath, content) {
+				log.Printf("Warning: %s is not in the earlier answer key and differs from what this run renders. Rewriting it.", filename)
+				skipped = false
+			}
+		}
+		if skipped && !g.opts.Force {
+			g.skippedCount++
+		} else if g.budgetSkipped > 0 || !g.fitsOutputBudget(content) {
+			if g.budgetSkipped == 0 {
+				log.Printf("Warning: Writing %s would exceed -max-output-bytes %d (%d bytes written so far). Skipping it and every remaining prompt file.", filename, g.opts.MaxOutputBytes, g.outputBytes)
+			}
+			g.budgetSkipped++
+			continue
+		} else {
+			skipped = false
+			writtenPath, err = g.writeFile(filepath, content)
+		}
+		if err != nil {
+			log.Printf("Error writing prompt file: %v", err)
+		} else {
+			filename += strings.TrimPrefix(writtenPath, filepath) // Picks up the .gz suffix when compressing
+			filepath = writtenPath
+			if !skipped {
+				if g.progress == nil && g.opts.TargetTokens > 0 {
+					fmt.Fprintf(g.out, "Successfully created: %s (~%d tokens)\n", filepath, rendered.TokenEstimate)
+				} else if g.progress == nil {
+					fmt.Fprintf(g.out, "Successfully created: %s\n", filepath)
+				}
+				g.generatedCount++
+			}
+			answer := PromptAnswer{Desc: config.Desc, File: filename, Lang: lang, Reordered: variant.reordered, Expected: expected}
+			if config.IsJSONOutput {
+				answer.ResponseFormat = RESPONSE_FORMAT_JSON_AGES
+			}
+			if config.IsMarkdownTable {
+				answer.ResponseFormat = RESPONSE_FORMAT_MARKDOWN_TABLE
+			}
+			if config.IsMultiCount || config.IsZeroCount {
+				answer.ResponseFormat = RESPONSE_FORMAT_COUNT
+			}
+			answer.AnswerRegexes = buildAnswerRegex(answer)
+			// A split instruction has no question without its lead-in, so it gets no baseline.
+			if g.opts.EmitBaseline && !config.IsSplitInstruction {
+				baselineFile := "prompt_" + name + ".baseline" + g.promptExt
+				var baselineBuf bytes.Buffer
+				_, err := renderPrompt(&baselineBuf, config.Desc, baselineTemplate(templateText), variantData, g.promptPrefix, g.markerInstruction)
+				baselineBuf.WriteString(annotation)
+				baseline := baselineBuf.Bytes()
+				if err == nil && g.opts.OutputStyle == "chat" {
+					baseline, err = encodeChatPrompt(g.systemPromptText, baselineBuf.String())
+				}
+				if err != nil {
+					log.Printf("Error rendering %s: %v", baselineFile, err)
+				} else if g.budgetSkipped > 0 || !g.fitsOutputBudget(baseline) {
+					if g.budgetSkipped == 0 {
+						log.Printf("Warning: Writing %s would exceed -max-output-bytes %d (%d bytes written so far). Skipping it and every remaining prompt file.", baselineFile, g.opts.MaxOutputBytes, g.outputBytes)
+					}
+					g.budgetSkipped++
+				} else if baselinePath, err := g.writeFile(baselineTarget, baseline); err != nil {
+					log.Printf("Error writing baseline prompt file: %v", err)
+				} else {
+					answer.BaselineFile = baselineFile + strings.TrimPrefix(baselinePath, baselineTarget)
+				}
+			}
+			if needles != nil {
+				answer.NeedleGap = g.opts.NeedleGap
+				answer.Needles = needles
+			}
+			if skipped {
+				if kept, ok := g.earlier.answers[strings.TrimSuffix(filename, ".gz")]; ok {
+					answer = kept
+				}
+			}
+			g.answers = append(g.answers, answer)
+			record := PromptRecord{
+				Desc:            config.Desc,
+				File:            filename,
+				Lang:            lang,
+				Reordered:       variant.reordered,
+				Type:            promptType(config),
+				TokenEstimate:   rendered.TokenEstimate,
+				MatchCount:      matchCount,
+				NeedlePositions: needlePositions(queriedNames, positionIndex, positionTotal),
+				Seed:            promptSeed,
+			}
+			record.Difficulty = scoreDifficulty(config, record)
+			record.Bucket = difficultyBucket(record.Difficulty)
+			if skipped {
+				if kept, ok := g.earlier.records[strings.TrimSuffix(filename, ".gz")]; ok {
+					record = kept
+				}
+			}
+			g.promptRecords = append(g.promptRecords, record)
+		}
+	}
+	// --- End File Writing Logic ---
+}
+
+// focusIndices clamps the two QueryIndices of a start/end focus config onto n entries. Tiny
+// datasets can clamp both onto the same entry, so the two ends are used instead; ok is false
+// when there are not two distinct entries to query.
+func focusIndices(indices []int, n int) (int, int, bool) {
+	idx1, idx2 := min(indices[0], n-1), min(indices[1], n-1)
+	if idx1 == idx2 && n >= 2 {
+		idx1, idx2 = 0, n-1
+	}
+	return idx1, idx2, idx1 >= 0 && idx2 >= 0 && idx1 != idx2
+}
@@ -0,0 +1,143 @@
+package promptgen
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testPeople returns the first n (at most 3) entries of a small fixed dataset. Each call returns
+// a new slice, so tests may modify it.
+func testPeople(n int) []PersonEntry {
+	return []PersonEntry{
+		{Name: "Queen Weber", Age: 49, City: "Tartu", JobTitle: "Scientist"},
+		{Name: "Dan Daugherty", Age: 41, City: "Osaka", JobTitle: "Chef"},
+		{Name: "Ann Lee", Age: 33, City: "Graz", JobTitle: "Pilot"},
+	}[:n]
+}
+
+// newBareGenerator returns a Generator with the default options and seed 1 that writes into a
+// temporary directory and has no data yet.
+func newBareGenerator(t testing.TB) *Generator {
+	t.Helper()
+	opts := DefaultOptions()
+	opts.Seed = 1
+	opts.OutputDir = t.TempDir()
+	g, err := NewGenerator(opts)
+	if err != nil {
+		t.Fatalf("NewGenerator: %v", err)
+	}
+	return g
+}
+
+// newTestGenerator returns a Generator over data that keeps written files in memory. Tests set
+// g.opts for the options they exercise before calling Generate.
+func newTestGenerator(t *testing.T, data []PersonEntry) (*Generator, map[string][]byte) {
+	t.Helper()
+	g := newBareGenerator(t)
+	g.SetData(data)
+	files := make(map[string][]byte)
+	g.Write = func(path string, content []byte) (string, error) {
+		files[path] = content
+		return path, nil
+	}
+	return g, files
+}
+
+func TestStartEndFocusIndicesOnTwoEntries(t *testing.T) {
+	data := testPeople(2)
+	template := `Dataset:\n{{.DataBlock}}\n\nWhat is the age of {{.QueryName1}} and the age of {{.QueryName2}} from this dataset?`
+	tests := []struct {
+		name    string
+		entries []PersonEntry
+		indices []int
+	}{
+		{name: "indices from the dataset size", entries: data, indices: []int{1, len(data) - 2}},
+		{name: "indices clamped onto one entry", entries: data, indices: []int{1, NUM_ENTRIES - 2}},
+		{name: "one entry", entries: data[:1], indices: []int{1, NUM_ENTRIES - 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, _ := newTestGenerator(t, tt.entries)
+			answers, _ := g.Generate(PromptConfig{Desc: "05_start_end_focus_2", QueryIndices: tt.indices, Template: template})
+			if len(tt.entries) < 2 {
+				if len(answers) != 0 {
+					t.Fatalf("generated %d answers from %d entry, want the config skipped", len(answers), len(tt.entries))
+				}
+				return
+			}
+			if len(answers) != 1 {
+				t.Fatalf("generated %d answers, want 1", len(answers))
+			}
+			ages, ok := answers[0].Expected.(map[string]int)
+			if !ok || len(ages) != 2 {
+				t.Fatalf("expected = %#v, want ages for two distinct people", answers[0].Expected)
+			}
+			for _, entry := range tt.entries {
+				if ages[entry.Name] != entry.Age {
+					t.Errorf("age of %s = %d, want %d", entry.Name, ages[entry.Name], entry.Age)
+				}
+			}
+		})
+	}
+}
+
+func TestSplitInstructionKeepsItsLeadIn(t *testing.T) {
+	data := testPeople(3)
+	g, files := newTestGenerator(t, data)
+	g.opts.QuestionsPerFile, g.opts.EmitBaseline = 2, true
+	answers, _ := g.Generate(PromptConfig{Desc: "54_split_instruction_3", QueryCount: 3, IsSplitInstruction: true,
+		Template: `Read the following list carefully.\n{{.DataBlock}}\n\nNow, what are the ages of:\n{{.QueryItemsFormatted}}`})
+	if len(g.bundled) != 0 {
+		t.Errorf("bundled %d questions, want the split instruction written alone", len(g.bundled))
+	}
+	if len(answers) != 1 || answers[0].BaselineFile != "" {
+		t.Fatalf("answers = %+v, want one without a baseline", answers)
+	}
+	if len(files) != 1 {
+		t.Errorf("wrote %d files, want only the prompt", len(files))
+	}
+	for path, content := range files {
+		if !strings.Contains(string(content), "Read the following list carefully.") {
+			t.Errorf("%s lost the instructions before the data block:\n%s", path, content)
+		}
+	}
+}
+
+func TestGeneratorSkipsWhatItCannotRender(t *testing.T) {
+	data := testPeople(3)
+	tests := []struct {
+		name          string
+		referenceYear int
+		visibleRows   int
+		config        PromptConfig
+	}{
+		{name: "-reference-year over data without birth years", referenceYear: 2020,
+			config: PromptConfig{Desc: "34_born_in_year", IsBornInYear: true, Template: `{{.DataBlock}}\n\nWho was born in {{.TargetBirthYear}}?`}},
+		{name: "more queried people than -visible-rows", visibleRows: 2,
+			config: PromptConfig{Desc: "39_many_needles", QueryCount: 3, Template: `{{.DataBlock}}\n\nAges of: {{.QueryItemsFormattedInline}}`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, files := newTestGenerator(t, data)
+			g.opts.ReferenceYear, g.opts.VisibleRows = tt.referenceYear, tt.visibleRows
+			if answers, _ := g.Generate(tt.config); len(answers) != 0 || len(files) != 0 {
+				t.Errorf("generated %d answers and %d files, want the config skipped", len(answers), len(files))
+			}
+		})
+	}
+}
+
+func TestGeneratorRewritesExistingFilesWithoutAnEarlierRun(t *testing.T) {
+	data := testPeople(2)
+	g, files := newTestGenerator(t, data)
+	path := filepath.Join(g.outputDir, "prompt_01_standard_retrieval_2.txt")
+	if err := os.WriteFile(path, []byte("left over"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	answers, _ := g.Generate(PromptConfig{Desc: "01_standard_retrieval_2", QueryCount: 2, Template: `{{.DataBlock}}\n\nAges of: {{.QueryItemsFormattedInline}}`})
+	if len(answers) != 1 || files[path] == nil {
+		t.Fatalf("generated %d answers and wrote %v, want %s rewritten", len(answers), files, path)
+	}
+}
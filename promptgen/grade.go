@@ -0,0 +1,829 @@
+package promptgen
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// --- Response Formats ---
+// RESPONSE_FORMAT_JSON_AGES marks prompts that ask for {"answers": [{"name": ..., "age": ...}]}.
+const RESPONSE_FORMAT_JSON_AGES = "json_ages"
+
+// RESPONSE_FORMAT_MARKDOWN_TABLE marks prompts that ask for a Name | Age | City Markdown table.
+const RESPONSE_FORMAT_MARKDOWN_TABLE = "markdown_table"
+
+// RESPONSE_FORMAT_COUNT marks prompts whose whole answer is one count; -grade reads a single
+// integer from the response (see -count-extract) instead of matching regexes.
+const RESPONSE_FORMAT_COUNT = "count"
+
+// --- Data Structures for Grading ---
+type AgeAnswerItem struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+type AgeAnswerResponse struct {
+	Answers []AgeAnswerItem `json:"answers"`
+}
+
+// SchemaError is one field-level problem found while validating a JSON response.
+type SchemaError struct {
+	Path    string
+	Problem string
+}
+
+func (e SchemaError) String() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Problem)
+}
+
+// GradeResult keeps format failures (SchemaErrors) apart from content errors (Score).
+type GradeResult struct {
+	Desc         string
+	File         string
+	Graded       bool
+	Missing      bool // No response file was found
+	SchemaErrors []SchemaError
+	Correct      int
+	Total        int
+	Score        float64
+	Notes        []string
+}
+
+// --- Function to Extract JSON from a Response ---
+// Models often wrap JSON in prose or ``` fences; take the outermost {...} span.
+func extractJSONObject(text string) string {
+	start := strings.Index(text, "{")
+	end := strings.LastIndex(text, "}")
+	if start < 0 || end < start {
+		return ""
+	}
+	return text[start : end+1]
+}
+
+// isJSONNull reports whether raw is the JSON literal null, which json.Unmarshal accepts for any
+// field and decodes as the zero value.
+func isJSONNull(raw json.RawMessage) bool {
+	return string(bytes.TrimSpace(raw)) == "null"
+}
+
+// ageFromText accepts a JSON string holding an age in digits ("42") or words ("forty-two").
+func ageFromText(raw json.RawMessage) (int, bool) {
+	var text string
+	if err := json.Unmarshal(raw, &text); err != nil {
+		return 0, false
+	}
+	if age, err := strconv.Atoi(strings.TrimSpace(text)); err == nil {
+		return age, true
+	}
+	return wordsToNumber(text)
+}
+
+// --- Function to Validate an Age-Answer JSON Response ---
+// validateAgeAnswerJSON checks the response against {"answers": [{"name": string, "age": integer}]},
+// collecting every field-level violation instead of stopping at the first one. Valid items are
+// returned in the typed response so content can still be scored.
+func validateAgeAnswerJSON(text string) (AgeAnswerResponse, []SchemaError) {
+	var response AgeAnswerResponse
+	raw := extractJSONObject(text)
+	if raw == "" {
+		return response, []SchemaError{{Path: "$", Problem: "no JSON object found"}}
+	}
+
+	var root map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(raw), &root); err != nil {
+		return response, []SchemaError{{Path: "$", Problem: fmt.Sprintf("invalid JSON object: %v", err)}}
+	}
+	answersRaw, ok := root["answers"]
+	if !ok {
+		return response, []SchemaError{{Path: "$.answers", Problem: "missing required field"}}
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(answersRaw, &items); err != nil || isJSONNull(answersRaw) {
+		return response, []SchemaError{{Path: "$.answers", Problem: "expected an array"}}
+	}
+
+	problems := []SchemaError{}
+	for i, itemRaw := range items {
+		path := fmt.Sprintf("$.answers[%d]", i)
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(itemRaw, &fields); err != nil || isJSONNull(itemRaw) {
+			problems = append(problems, SchemaError{Path: path, Problem: "expected an object"})
+			continue
+		}
+		var item AgeAnswerItem
+		valid := true
+		if nameRaw, ok := fields["name"]; !ok {
+			problems = append(problems, SchemaError{Path: path + ".name", Problem: "missing required field"})
+			valid = false
+		} else if isJSONNull(nameRaw) {
+			problems = append(problems, SchemaError{Path: path + ".name", Problem: "null instead of a string"})
+			valid = false
+		} else if err := json.Unmarshal(nameRaw, &item.Name); err != nil {
+			problems = append(problems, SchemaError{Path: path + ".name", Problem: "expected a string"})
+			valid = false
+		}
+		if ageRaw, ok := fields["age"]; !ok {
+			problems = append(problems, SchemaError{Path: path + ".age", Problem: "missing required field"})
+			valid = false
+		} else if isJSONNull(ageRaw) {
+			problems = append(problems, SchemaError{Path: path + ".age", Problem: "null instead of an integer"})
+			valid = false
+		} else if err := json.Unmarshal(ageRaw, &item.Age); err != nil {
+			// With -age-style words the model may answer in the form it read ("forty-two").
+			age, ok := ageFromText(ageRaw)
+			if !ok {
+				problems = append(problems, SchemaError{Path: path + ".age", Problem: "expected an integer or a number in words"})
+				valid = false
+			}
+			item.Age = age
+		}
+		if valid {
+			response.Answers = append(response.Answers, item)
+		}
+	}
+	return response, problems
+}
+
+// --- Function to Load an Answer Key ---
+// loadAnswerKey reads answer_key.json or answer_key.yaml (either possibly gzipped) from dir.
+func loadAnswerKey(dir string) ([]PromptAnswer, error) {
+	var answers []PromptAnswer
+	if content, err := readOutputFile(filepath.Join(dir, ANSWER_KEY_BASENAME+".json")); err == nil {
+		if err := json.Unmarshal(content, &answers); err != nil {
+			return nil, fmt.Errorf("decoding answer key: %w", err)
+		}
+		return answers, nil
+	}
+	content, err := readOutputFile(filepath.Join(dir, ANSWER_KEY_BASENAME+".yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("no %s.json or %s.yaml (optionally .gz) in %s", ANSWER_KEY_BASENAME, ANSWER_KEY_BASENAME, dir)
+	}
+	if err := yaml.Unmarshal(content, &answers); err != nil {
+		return nil, fmt.Errorf("decoding answer key: %w", err)
+	}
+	return answers, nil
+}
+
+// --- Function to Load a Manifest ---
+func loadManifest(dir string) (RunManifest, error) {
+	var manifest RunManifest
+	content, err := readOutputFile(filepath.Join(dir, MANIFEST_FILENAME))
+	if err != nil {
+		return manifest, err
+	}
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return manifest, fmt.Errorf("decoding manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+// responseFileFor maps prompt_<desc>.txt or .json (optionally .gz) to response_<desc>.txt.
+func responseFileFor(promptFile string) string {
+	base := strings.TrimSuffix(strings.TrimSuffix(promptFile, ".gz"), ".json")
+	base = strings.TrimSuffix(base, ".txt") + ".txt"
+	return strings.Replace(base, "prompt_", "response_", 1)
+}
+
+// expectedAges converts a decoded name->age answer back into typed form.
+func expectedAges(expected interface{}) (map[string]int, bool) {
+	raw, ok := expected.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	ages := make(map[string]int, len(raw))
+	for name, value := range raw {
+		age, ok := wholeNumber(value)
+		if !ok {
+			return nil, false
+		}
+		ages[name] = age
+	}
+	return ages, true
+}
+
+// expectedEntries converts a decoded list of person records back into typed form.
+func expectedEntries(expected interface{}) ([]PersonEntry, bool) {
+	if entries, ok := expected.([]PersonEntry); ok {
+		return entries, true
+	}
+	encoded, err := json.Marshal(expected)
+	if err != nil {
+		return nil, false
+	}
+	var entries []PersonEntry
+	if err := json.Unmarshal(encoded, &entries); err != nil {
+		return nil, false
+	}
+	return entries, true
+}
+
+// --- Functions to Parse a Markdown Table Response ---
+// splitTableRow splits "| a | b |" into its trimmed cells; the outer pipes are optional.
+func splitTableRow(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimSuffix(strings.TrimPrefix(line, "|"), "|")
+	cells := strings.Split(line, "|")
+	for i, cell := range cells {
+		cells[i] = strings.TrimSpace(cell)
+	}
+	return cells
+}
+
+var tableSeparatorCell = regexp.MustCompile(`^:?-{3,}:?$`)
+
+// parseMarkdownTable reads the first pipe table in text: a header row, a |---| separator row and
+// the data rows after it. Rows whose cell count differs from the header are reported and dropped.
+func parseMarkdownTable(text string) (header []string, rows [][]string, problems []SchemaError) {
+	lines := []string{}
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "|") {
+			lines = append(lines, line)
+		} else if len(lines) > 0 {
+			break // The table ended
+		}
+	}
+	if len(lines) < 2 {
+		return nil, nil, []SchemaError{{Path: "$", Problem: "no Markdown table found"}}
+	}
+	header = splitTableRow(lines[0])
+	separator := splitTableRow(lines[1])
+	for _, cell := range separator {
+		if !tableSeparatorCell.MatchString(cell) {
+			return nil, nil, []SchemaError{{Path: "row 1", Problem: "expected a |---| separator row after the header"}}
+		}
+	}
+	for i, line := range lines[2:] {
+		cells := splitTableRow(line)
+		if len(cells) != len(header) {
+			problems = append(problems, SchemaError{Path: fmt.Sprintf("row %d", i+2), Problem: fmt.Sprintf("has %d cells, the header has %d", len(cells), len(header))})
+			continue
+		}
+		rows = append(rows, cells)
+	}
+	return header, rows, problems
+}
+
+// gradeMarkdownTable checks a Name | Age | City table against the expected records. Missing
+// columns or rows with the wrong number of cells are schema errors; a row is correct when its
+// name, age and city all match.
+func gradeMarkdownTable(result *GradeResult, want []PersonEntry, response string) {
+	result.Graded = true
+	result.Total = len(want)
+	header, rows, problems := parseMarkdownTable(response)
+	result.SchemaErrors = problems
+	if header == nil {
+		return
+	}
+	columns := map[string]int{}
+	for i, cell := range header {
+		columns[strings.ToLower(cell)] = i
+	}
+	for _, column := range []string{"name", "age", "city"} {
+		if _, ok := columns[column]; !ok {
+			result.SchemaErrors = append(result.SchemaErrors, SchemaError{Path: "header", Problem: fmt.Sprintf("missing column %q", column)})
+		}
+	}
+	if len(result.SchemaErrors) > len(problems) {
+		return
+	}
+	got := make(map[string][]string, len(rows))
+	for _, cells := range rows {
+		got[cells[columns["name"]]] = cells
+	}
+	for _, entry := range want {
+		cells, found := got[entry.Name]
+		if !found {
+			result.Notes = append(result.Notes, fmt.Sprintf("missing %s", entry.Name))
+			continue
+		}
+		delete(got, entry.Name)
+		age, err := strconv.Atoi(cells[columns["age"]])
+		if err != nil {
+			var ok bool
+			if age, ok = wordsToNumber(cells[columns["age"]]); !ok {
+				age = -1
+			}
+		}
+		if age != entry.Age || !strings.EqualFold(cells[columns["city"]], entry.City) {
+			result.Notes = append(result.Notes, fmt.Sprintf("%s: got %s, %s; want %d, %s", entry.Name, cells[columns["age"]], cells[columns["city"]], entry.Age, entry.City))
+			continue
+		}
+		result.Correct++
+	}
+	for name := range got {
+		result.Notes = append(result.Notes, fmt.Sprintf("unexpected %s", name))
+	}
+}
+
+// --- Functions to Build Answer Regexes ---
+// buildAnswerRegex returns patterns a correct free-text response must all match, for graders that
+// cannot rely on structured output: each expected name, a name followed by its age, a count as a
+// standalone number, and so on. Patterns are case-insensitive and use RE2 syntax, which Python's
+// re module also accepts. Answers with nothing checkable by regex get none.
+func buildAnswerRegex(answer PromptAnswer) []string {
+	seen := make(map[string]bool)
+	patterns := []string{}
+	for _, pattern := range answerPatterns(answer.Expected) {
+		if !seen[pattern] {
+			seen[pattern] = true
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
+}
+
+func answerPatterns(expected interface{}) []string {
+	switch value := expected.(type) {
+	case map[string]int:
+		return nameValuePatterns(value)
+	case []string:
+		return namePatterns(value)
+	case int:
+		return []string{numberPattern(value)}
+	case []PersonEntry:
+		names := make([]string, len(value))
+		for i, entry := range value {
+			names[i] = entry.Name
+		}
+		return namePatterns(names)
+	case []NameAge:
+		// Order matters for sorted answers, so the names must appear in sequence.
+		names := make([]string, len(value))
+		for i, item := range value {
+			names[i] = item.Name
+		}
+		return []string{wordSequencePattern(names)}
+	case map[string][]string:
+		if present, ok := value["present"]; ok {
+			patterns := []string{}
+			for _, name := range present {
+				patterns = append(patterns, "(?i)"+wordPattern(name)+`[^\n]{0,40}?\bpresent\b`)
+			}
+			for _, name := range value["absent"] {
+				patterns = append(patterns, "(?i)"+wordPattern(name)+`[^\n]{0,40}?\babsent\b`)
+			}
+			return patterns
+		}
+		names := []string{}
+		for _, group := range value {
+			names = append(names, group...)
+		}
+		sort.Strings(names)
+		return namePatterns(names)
+	case map[string]interface{}:
+		patterns := []string{}
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			switch key {
+			case "ages", "names", "matches", "ranking", "names_with_age", "cities", "current_ages":
+				patterns = append(patterns, answerPatterns(value[key])...)
+			case "count", "sum", "difference", "distinct_cities", "over_count", "under_count", "years_until",
+				"first_count", "second_count":
+				patterns = append(patterns, answerPatterns(value[key])...)
+			case "signed_difference":
+				if n, ok := value[key].(int); ok {
+					patterns = append(patterns, signedNumberPattern(n))
+				}
+			case "name", "city", "country", "mode", "region":
+				if text, ok := value[key].(string); ok && text != "" {
+					patterns = append(patterns, "(?i)"+wordPattern(text))
+				}
+			case "ordered_names":
+				// Each name must appear, and one more pattern checks they appear in this order.
+				if names, ok := value[key].([]string); ok && len(names) > 0 {
+					patterns = append(patterns, namePatterns(names)...)
+					patterns = append(patterns, wordSequencePattern(names))
+				}
+			case "accepted_cities":
+				// Naming any one of them is enough.
+				if cities, ok := value[key].([]string); ok && len(cities) > 0 {
+					alternatives := make([]string, len(cities))
+					for i, city := range cities {
+						alternatives[i] = regexp.QuoteMeta(city)
+					}
+					patterns = append(patterns, `(?i)(?:^|`+wordEdge+`)(?:`+strings.Join(alternatives, "|")+`)(?:$|`+wordEdge+`)`)
+				}
+			case "answers":
+				if answers, ok := value[key].(map[string]interface{}); ok {
+					patterns = append(patterns, mixedAnswerPatterns(answers)...)
+				}
+			case "entry":
+				if entry, ok := value[key].(PersonEntry); ok {
+					patterns = append(patterns, nameValuePatterns(map[string]int{entry.Name: entry.Age})...)
+				}
+			}
+		}
+		return patterns
+	}
+	return nil
+}
+
+// wordEdge matches a character that cannot be part of a word. RE2's \b only treats ASCII letters as
+// word characters, so it finds no boundary before "Émile" and one inside "Zoëlle".
+const wordEdge = `[^\p{L}\p{N}_]`
+
+// wordPattern matches text as a whole word or phrase.
+func wordPattern(text string) string {
+	return `(?:^|` + wordEdge + `)` + regexp.QuoteMeta(text) + `(?:$|` + wordEdge + `)`
+}
+
+// wordSequencePattern matches words as whole words in this order. Each word's closing edge
+// character may be the next word's opening one, so "Ann Lee Bo Li" matches Ann Lee then Bo Li.
+func wordSequencePattern(words []string) string {
+	parts := make([]string, len(words))
+	for i, word := range words {
+		parts[i] = regexp.QuoteMeta(word) + `(?:$|` + wordEdge + `)`
+	}
+	return `(?is)(?:^|` + wordEdge + `)` + strings.Join(parts, `(?:.*`+wordEdge+`)?`)
+}
+
+// numberAlternative matches n in digits or spelled out, ending at a word boundary.
+func numberAlternative(n int) string {
+	digits := strconv.Itoa(n)
+	words := numberToWords(n)
+	if words == digits {
+		return digits + `\b`
+	}
+	return `(?:` + digits + `|` + strings.ReplaceAll(regexp.QuoteMeta(words), "-", "[- ]") + `)\b`
+}
+
+// numberPattern matches n as a standalone number.
+func numberPattern(n int) string {
+	return `(?i)(?:^|[^0-9])` + numberAlternative(n)
+}
+
+// signedNumberPattern matches n with its sign: "-7", "minus seven" or "negative 7" for negative n,
+// and a number not preceded by a minus sign otherwise.
+func signedNumberPattern(n int) string {
+	if n < 0 {
+		return `(?i)(?:-|−|\bminus |\bnegative )` + numberAlternative(-n)
+	}
+	return `(?i)(?:^|[^0-9\-−])` + numberAlternative(n)
+}
+
+func namePatterns(names []string) []string {
+	patterns := make([]string, len(names))
+	for i, name := range names {
+		patterns[i] = "(?i)" + wordPattern(name)
+	}
+	return patterns
+}
+
+// nameValuePatterns requires each name to be followed by its number before any other digit appears.
+func nameValuePatterns(values map[string]int) []string {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	patterns := make([]string, len(names))
+	for i, name := range names {
+		patterns[i] = "(?i)" + wordPattern(name) + `[^0-9\n]{0,40}?` + numberAlternative(values[name])
+	}
+	return patterns
+}
+
+// mixedAnswerPatterns handles name -> age-or-ABSENT_AGE_ANSWER maps.
+func mixedAnswerPatterns(answers map[string]interface{}) []string {
+	ages := make(map[string]int)
+	patterns := []string{}
+	for name, answer := range answers {
+		switch value := answer.(type) {
+		case int:
+			ages[name] = value
+		case string:
+			patterns = append(patterns, "(?i)"+wordPattern(name)+`[^0-9\n]{0,40}?`+regexp.QuoteMeta(value))
+		}
+	}
+	sort.Strings(patterns)
+	return append(nameValuePatterns(ages), patterns...)
+}
+
+// matchAnswerRegexes grades a free-text response by the fraction of patterns it matches.
+func matchAnswerRegexes(result *GradeResult, patterns []string, response string) {
+	result.Graded = true
+	result.Total = len(patterns)
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			result.Notes = append(result.Notes, fmt.Sprintf("invalid answer regex %q: %v", pattern, err))
+			continue
+		}
+		if re.MatchString(response) {
+			result.Correct++
+		} else {
+			result.Notes = append(result.Notes, fmt.Sprintf("no match for %s", pattern))
+		}
+	}
+}
+
+// integerPattern matches an integer written in digits, with or without thousands commas.
+var integerPattern = regexp.MustCompile(`\b\d{1,3}(?:,\d{3})+\b|\d+`)
+
+// spelledIntegerPattern matches an integer spelled out the way numberToWords writes it, tolerating
+// spaces and "and" ("Forty two", "one hundred and five"), or "no"/"none" for zero.
+var spelledIntegerPattern = func() *regexp.Regexp {
+	words := []string{"hundred"}
+	words = append(words, onesWords...)
+	for _, tens := range tensWords {
+		if tens != "" {
+			words = append(words, tens)
+		}
+	}
+	// Longer words first, so "seventeen" is not read as "seven".
+	sort.SliceStable(words, func(i, j int) bool { return len(words[i]) > len(words[j]) })
+	word := `(?:` + strings.Join(words, "|") + `)`
+	return regexp.MustCompile(`(?i)\b(?:none|no|` + word + `(?:[\s-]+(?:and[\s-]+)?` + word + `)*)\b`)
+}()
+
+// spelledInteger reads a spelledIntegerPattern match.
+func spelledInteger(text string) (int, bool) {
+	switch strings.ToLower(text) {
+	case "no", "none":
+		return 0, true
+	}
+	return wordsToNumber(text)
+}
+
+// extractInteger returns the first or last (which) integer in text; "1,204" reads as 1204. Text
+// without digits is read for spelled-out integers instead, so "forty-two" gives 42 and "none" 0.
+func extractInteger(text, which string) (int, bool) {
+	matches := integerPattern.FindAllString(text, -1)
+	parse := func(match string) (int, bool) {
+		n, err := strconv.Atoi(strings.ReplaceAll(match, ",", ""))
+		return n, err == nil
+	}
+	if len(matches) == 0 {
+		matches, parse = spelledIntegerPattern.FindAllString(text, -1), spelledInteger
+	}
+	if len(matches) == 0 {
+		return 0, false
+	}
+	match := matches[0]
+	if which == "last" {
+		match = matches[len(matches)-1]
+	}
+	return parse(match)
+}
+
+// wholeNumber reads a decoded integer: YAML yields an int (or int64 for large values) and JSON a
+// float64, which must have no fractional part.
+func wholeNumber(decoded interface{}) (int, bool) {
+	switch value := decoded.(type) {
+	case int:
+		return value, true
+	case int64:
+		return int(value), true
+	case float64:
+		return int(value), value == float64(int(value))
+	}
+	return 0, false
+}
+
+// expectedCount reads a count answer.
+func expectedCount(expected interface{}) (int, bool) {
+	return wholeNumber(expected)
+}
+
+// --- Function to Grade One Response ---
+// countExtract ("first" or "last") picks which integer in the response answers a count prompt.
+func gradeResponse(answer PromptAnswer, response, countExtract string) GradeResult {
+	result := GradeResult{Desc: answer.Desc, File: answer.File}
+	switch answer.ResponseFormat {
+	case RESPONSE_FORMAT_JSON_AGES:
+		want, ok := expectedAges(answer.Expected)
+		if !ok {
+			result.Notes = append(result.Notes, "answer key is not a name->age map")
+			return result
+		}
+		parsed, problems := validateAgeAnswerJSON(response)
+		result.Graded = true
+		result.SchemaErrors = problems
+		result.Total = len(want)
+		got := make(map[string]int, len(parsed.Answers))
+		for _, item := range parsed.Answers {
+			got[strings.TrimSpace(item.Name)] = item.Age
+		}
+		for name, age := range want {
+			if gotAge, found := got[name]; !found {
+				result.Notes = append(result.Notes, fmt.Sprintf("missing %s", name))
+			} else if gotAge != age {
+				result.Notes = append(result.Notes, fmt.Sprintf("%s: got %d, want %d", name, gotAge, age))
+			} else {
+				result.Correct++
+			}
+		}
+		for name := range got {
+			if _, wanted := want[name]; !wanted {
+				result.Notes = append(result.Notes, fmt.Sprintf("unexpected %s", name))
+			}
+		}
+	case RESPONSE_FORMAT_MARKDOWN_TABLE:
+		want, ok := expectedEntries(answer.Expected)
+		if !ok {
+			result.Notes = append(result.Notes, "answer key is not a list of records")
+			return result
+		}
+		gradeMarkdownTable(&result, want, response)
+	case RESPONSE_FORMAT_COUNT:
+		want, ok := expectedCount(answer.Expected)
+		if !ok {
+			result.Notes = append(result.Notes, "answer key is not a count")
+			return result
+		}
+		result.Graded = true
+		result.Total = 1
+		if got, found := extractInteger(response, countExtract); !found {
+			result.Notes = append(result.Notes, "no integer in response")
+		} else if got != want {
+			result.Notes = append(result.Notes, fmt.Sprintf("%s integer is %d, want %d", countExtract, got, want))
+		} else {
+			result.Correct++
+		}
+	default:
+		if len(answer.AnswerRegexes) > 0 {
+			matchAnswerRegexes(&result, answer.AnswerRegexes, response)
+		} else {
+			result.Notes = append(result.Notes, "no grader for this prompt type")
+		}
+	}
+	if result.Total > 0 {
+		result.Score = float64(result.Correct) / float64(result.Total)
+	}
+	sort.Strings(result.Notes)
+	return result
+}
+
+// --- Function to Grade a Directory of Responses ---
+// gradeResponses pairs each answer in answerDir's key with response_<desc>.txt in responseDir and
+// grades them on a pool of workers. Each worker writes only its own slots of results, so no lock
+// is needed, and the results come back in answer-key order regardless of scheduling.
+func gradeResponses(answerDir, responseDir string, workers int, countExtract string) ([]GradeResult, error) {
+	answers, err := loadAnswerKey(answerDir)
+	if err != nil {
+		return nil, err
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	results := make([]GradeResult, len(answers))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				answer := answers[i]
+				content, err := os.ReadFile(filepath.Join(responseDir, responseFileFor(answer.File)))
+				if err != nil {
+					results[i] = GradeResult{Desc: answer.Desc, File: answer.File, Missing: true}
+					continue
+				}
+				results[i] = gradeResponse(answer, string(content), countExtract)
+			}
+		}()
+	}
+	for i := range answers {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results, nil
+}
+
+// Grade scores the responses in opts.GradeDir against the answer key in opts.GradeAnswersDir,
+// prints the report and, with opts.ResultsCSV set, writes the scores next to the manifest's
+// prompt records.
+func Grade(opts Options) error {
+	out := opts.out()
+	results, err := gradeResponses(opts.GradeAnswersDir, opts.GradeDir, opts.Concurrency, opts.CountExtract)
+	if err != nil {
+		return fmt.Errorf("grading responses: %w", err)
+	}
+	printGradeReport(out, results)
+	if opts.ResultsCSV != "" {
+		manifest, err := loadManifest(opts.GradeAnswersDir)
+		if err != nil {
+			return fmt.Errorf("loading manifest for -results-csv: %w", err)
+		}
+		if err := writeResultsCSV(opts.ResultsCSV, manifest.Prompts, results, opts.CSVBOM); err != nil {
+			return fmt.Errorf("writing results CSV: %w", err)
+		}
+		fmt.Fprintf(out, "Results CSV written to: %s\n", opts.ResultsCSV)
+	}
+	return nil
+}
+
+// --- Function to Print a Grading Report ---
+func printGradeReport(w io.Writer, results []GradeResult) {
+	graded, formatFailures := 0, 0
+	totalScore := 0.0
+	for _, result := range results {
+		switch {
+		case result.Missing:
+			fmt.Fprintf(w, "%-40s no response file (%s)\n", result.Desc, responseFileFor(result.File))
+		case !result.Graded:
+			fmt.Fprintf(w, "%-40s not graded: %s\n", result.Desc, strings.Join(result.Notes, "; "))
+		default:
+			graded++
+			totalScore += result.Score
+			format := "format OK"
+			if len(result.SchemaErrors) > 0 {
+				formatFailures++
+				format = fmt.Sprintf("%d schema error(s)", len(result.SchemaErrors))
+			}
+			fmt.Fprintf(w, "%-40s %s, content %d/%d (%.2f)\n", result.Desc, format, result.Correct, result.Total, result.Score)
+			for _, problem := range result.SchemaErrors {
+				fmt.Fprintf(w, "    schema: %s\n", problem)
+			}
+			for _, note := range result.Notes {
+				fmt.Fprintf(w, "    content: %s\n", note)
+			}
+		}
+	}
+	if graded > 0 {
+		fmt.Fprintf(w, "\nGraded %d response(s): mean content score %.3f, %d with format violations.\n", graded, totalScore/float64(graded), formatFailures)
+	} else {
+		fmt.Fprintln(w, "\nNo responses were graded.")
+	}
+}
+
+// --- Function to Write Results CSV ---
+// writeResultsCSV writes one row per prompt record, sorted by desc and then lang. The score column
+// is filled from results (matched by file) and left empty for prompts that were not graded. With
+// bom (-csv-bom) the file starts with UTF8_BOM.
+func writeResultsCSV(path string, records []PromptRecord, results []GradeResult, bom bool) error {
+	scores := make(map[string]float64, len(results))
+	for _, result := range results {
+		if result.Graded {
+			scores[result.File] = result.Score
+		}
+	}
+	sorted := append([]PromptRecord{}, records...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Desc != sorted[j].Desc {
+			return sorted[i].Desc < sorted[j].Desc
+		}
+		return sorted[i].Lang < sorted[j].Lang
+	})
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if bom {
+		if _, err := file.WriteString(UTF8_BOM); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	w := csv.NewWriter(file)
+	w.Write([]string{"desc", "lang", "type", "token_estimate", "match_count", "needle_position", "difficulty", "score"})
+	for _, record := range sorted {
+		needlePosition := ""
+		if len(record.NeedlePositions) > 0 {
+			sum := 0.0
+			for _, position := range record.NeedlePositions {
+				sum += position
+			}
+			needlePosition = strconv.FormatFloat(sum/float64(len(record.NeedlePositions)), 'f', 3, 64)
+		}
+		score := ""
+		if value, ok := scores[record.File]; ok {
+			score = strconv.FormatFloat(value, 'f', 3, 64)
+		}
+		w.Write([]string{
+			record.Desc,
+			record.Lang,
+			record.Type,
+			strconv.Itoa(record.TokenEstimate),
+			strconv.Itoa(record.MatchCount),
+			needlePosition,
+			strconv.FormatFloat(record.Difficulty, 'f', 3, 64),
+			score,
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
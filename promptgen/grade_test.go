@@ -0,0 +1,196 @@
+package promptgen
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateAgeAnswerJSONRejectsNullAndMissing(t *testing.T) {
+	tests := []struct {
+		name      string
+		response  string
+		wantPath  string
+		wantValid int
+	}{
+		{name: "valid", response: `{"answers": [{"name": "Queen Weber", "age": 49}]}`, wantValid: 1},
+		{name: "age in words", response: `{"answers": [{"name": "Queen Weber", "age": "forty-nine"}]}`, wantValid: 1},
+		{name: "null age", response: `{"answers": [{"name": "Queen Weber", "age": null}]}`, wantPath: "$.answers[0].age"},
+		{name: "missing age", response: `{"answers": [{"name": "Queen Weber"}]}`, wantPath: "$.answers[0].age"},
+		{name: "null name", response: `{"answers": [{"name": null, "age": 49}]}`, wantPath: "$.answers[0].name"},
+		{name: "null item", response: `{"answers": [null]}`, wantPath: "$.answers[0]"},
+		{name: "null answers", response: `{"answers": null}`, wantPath: "$.answers"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, problems := validateAgeAnswerJSON(tt.response)
+			if len(parsed.Answers) != tt.wantValid {
+				t.Errorf("%d valid answers, want %d", len(parsed.Answers), tt.wantValid)
+			}
+			if tt.wantPath == "" {
+				if len(problems) > 0 {
+					t.Errorf("unexpected schema errors: %v", problems)
+				}
+				return
+			}
+			if len(problems) != 1 || problems[0].Path != tt.wantPath {
+				t.Errorf("schema errors = %v, want one at %s", problems, tt.wantPath)
+			}
+		})
+	}
+}
+
+func TestBuildAnswerRegexMatchesWholeWords(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected interface{}
+		response string
+		want     bool
+	}{
+		{name: "names", expected: []string{"Ann Lee", "Bo Li"}, response: "Bo Li and Ann Lee.", want: true},
+		{name: "name inside a longer name", expected: []string{"Ann Lee"}, response: "Ann Leeson", want: false},
+		{name: "non-ASCII first letter", expected: []string{"Émile Zola"}, response: "Answer: Émile Zola.", want: true},
+		{name: "non-ASCII last letter inside a longer name", expected: []string{"Zoë"}, response: "Zoëlle", want: false},
+		{name: "non-ASCII name and age", expected: map[string]int{"Zoë Ångström": 34}, response: "- Zoë Ångström: 34", want: true},
+		{name: "name and wrong age", expected: map[string]int{"Zoë Ångström": 34}, response: "- Zoë Ångström: 43", want: false},
+		{name: "sorted names next to each other", expected: []NameAge{{Name: "Ann Lee", Age: 30}, {Name: "Bo Li", Age: 40}}, response: "Ann Lee Bo Li", want: true},
+		{name: "sorted names out of order", expected: []NameAge{{Name: "Ann Lee", Age: 30}, {Name: "Bo Li", Age: 40}}, response: "Bo Li, Ann Lee", want: false},
+		{name: "city", expected: map[string]interface{}{"city": "Łódź"}, response: "They live in Łódź.", want: true},
+		{name: "city inside a longer word", expected: map[string]interface{}{"city": "Łódź"}, response: "Łódźki", want: false},
+		{name: "accepted city spelling", expected: map[string]interface{}{"accepted_cities": []string{"Malmö", "Malmo"}}, response: "Malmö", want: true},
+		{name: "accepted city inside a longer word", expected: map[string]interface{}{"accepted_cities": []string{"Malmö", "Malmo"}}, response: "Malmöhus", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patterns := buildAnswerRegex(PromptAnswer{Expected: tt.expected})
+			if len(patterns) == 0 {
+				t.Fatalf("no patterns for %#v", tt.expected)
+			}
+			result := GradeResult{}
+			matchAnswerRegexes(&result, patterns, tt.response)
+			if got := result.Correct == result.Total; got != tt.want {
+				t.Errorf("%q matches all of %q = %v, want %v (%v)", tt.response, patterns, got, tt.want, result.Notes)
+			}
+		})
+	}
+}
+
+func TestExtractInteger(t *testing.T) {
+	tests := []struct {
+		text   string
+		which  string
+		want   int
+		wantOK bool
+	}{
+		{text: "There are 12 of them.", which: "first", want: 12, wantOK: true},
+		{text: "I first counted 12, but the answer is 14.", which: "first", want: 12, wantOK: true},
+		{text: "I first counted 12, but the answer is 14.", which: "last", want: 14, wantOK: true},
+		{text: "The total is 1,204 people.", which: "first", want: 1204, wantOK: true},
+		{text: "Between 3 and 1,204,000.", which: "last", want: 1204000, wantOK: true},
+		{text: "Forty-two people.", which: "first", want: 42, wantOK: true},
+		{text: "one hundred and five", which: "first", want: 105, wantOK: true},
+		{text: "Seventeen of them, not seven.", which: "first", want: 17, wantOK: true},
+		{text: "Seventeen of them, not seven.", which: "last", want: 7, wantOK: true},
+		{text: "None.", which: "first", want: 0, wantOK: true},
+		{text: "No one lives there.", which: "first", want: 0, wantOK: true},
+		{text: "No, there are 3.", which: "first", want: 3, wantOK: true},
+		{text: "I cannot tell from the list.", which: "first", wantOK: false},
+	}
+	for _, tt := range tests {
+		got, ok := extractInteger(tt.text, tt.which)
+		if ok != tt.wantOK || got != tt.want {
+			t.Errorf("extractInteger(%q, %s) = %d, %v; want %d, %v", tt.text, tt.which, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestGradeResponse(t *testing.T) {
+	ages := map[string]int{"Queen Weber": 49, "Dan Daugherty": 41}
+	decodedAges := map[string]interface{}{"Queen Weber": float64(49), "Dan Daugherty": float64(41)} // As loadAnswerKey returns them
+	records := testPeople(2)
+	tests := []struct {
+		name         string
+		answer       PromptAnswer
+		response     string
+		countExtract string
+		wantCorrect  int
+		wantTotal    int
+		wantSchema   int
+	}{
+		{name: "JSON ages", answer: PromptAnswer{ResponseFormat: RESPONSE_FORMAT_JSON_AGES, Expected: decodedAges},
+			response:    "```json\n{\"answers\": [{\"name\": \"Queen Weber\", \"age\": 49}, {\"name\": \"Dan Daugherty\", \"age\": \"forty-one\"}]}\n```",
+			wantCorrect: 2, wantTotal: 2},
+		{name: "JSON ages with a wrong age and a null", answer: PromptAnswer{ResponseFormat: RESPONSE_FORMAT_JSON_AGES, Expected: decodedAges},
+			response:    `{"answers": [{"name": "Queen Weber", "age": 48}, {"name": "Dan Daugherty", "age": null}]}`,
+			wantCorrect: 0, wantTotal: 2, wantSchema: 1},
+		{name: "markdown table", answer: PromptAnswer{ResponseFormat: RESPONSE_FORMAT_MARKDOWN_TABLE, Expected: records},
+			response:    "Here it is:\n\n| Name | Age | City |\n|---|---|---|\n| Dan Daugherty | 41 | osaka |\n| Queen Weber | forty-nine | Tartu |\n",
+			wantCorrect: 2, wantTotal: 2},
+		{name: "markdown table with a short row and no city column", answer: PromptAnswer{ResponseFormat: RESPONSE_FORMAT_MARKDOWN_TABLE, Expected: records},
+			response:    "| Name | Age |\n|---|---|\n| Queen Weber | 49 |\n| Dan Daugherty |\n",
+			wantCorrect: 0, wantTotal: 2, wantSchema: 2},
+		{name: "count, first integer", answer: PromptAnswer{ResponseFormat: RESPONSE_FORMAT_COUNT, Expected: 12},
+			response: "12 people, out of 5,000.", countExtract: "first", wantCorrect: 1, wantTotal: 1},
+		{name: "count, last integer", answer: PromptAnswer{ResponseFormat: RESPONSE_FORMAT_COUNT, Expected: 12},
+			response: "12 people, out of 5,000.", countExtract: "last", wantCorrect: 0, wantTotal: 1},
+		{name: "count from a decoded JSON key", answer: PromptAnswer{ResponseFormat: RESPONSE_FORMAT_COUNT, Expected: float64(3)},
+			response: "Three.", countExtract: "first", wantCorrect: 1, wantTotal: 1},
+		{name: "answer regexes", answer: PromptAnswer{AnswerRegexes: buildAnswerRegex(PromptAnswer{Expected: ages})},
+			response: "Queen Weber is 49 and Dan Daugherty is 14.", wantCorrect: 1, wantTotal: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := gradeResponse(tt.answer, tt.response, tt.countExtract)
+			if !result.Graded || result.Correct != tt.wantCorrect || result.Total != tt.wantTotal || len(result.SchemaErrors) != tt.wantSchema {
+				t.Errorf("graded %v, %d/%d with schema errors %v; want %d/%d with %d schema errors (notes %v)",
+					result.Graded, result.Correct, result.Total, result.SchemaErrors, tt.wantCorrect, tt.wantTotal, tt.wantSchema, result.Notes)
+			}
+		})
+	}
+}
+
+func TestGradeResponsesKeepsAnswerKeyOrder(t *testing.T) {
+	answerDir, responseDir := t.TempDir(), t.TempDir()
+	answers := []PromptAnswer{}
+	responses := map[string]string{}
+	for i := 1; i <= 12; i++ {
+		answer := PromptAnswer{Desc: fmt.Sprintf("%02d_count", i), File: fmt.Sprintf("prompt_%02d_count.txt", i), Expected: i, ResponseFormat: RESPONSE_FORMAT_COUNT}
+		answers = append(answers, answer)
+		switch {
+		case i%4 == 0:
+			// No response file.
+		case i%3 == 0:
+			responses[answer.File] = "Roughly 1,000."
+		default:
+			responses[answer.File] = fmt.Sprintf("There are %d.", i)
+		}
+	}
+	if _, err := newBareGenerator(t).writeAnswerKey(answerDir, answers, "json"); err != nil {
+		t.Fatalf("writeAnswerKey: %v", err)
+	}
+	for file, response := range responses {
+		if err := os.WriteFile(filepath.Join(responseDir, responseFileFor(file)), []byte(response), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results, err := gradeResponses(answerDir, responseDir, 4, "first")
+	if err != nil {
+		t.Fatalf("gradeResponses: %v", err)
+	}
+	if len(results) != len(answers) {
+		t.Fatalf("got %d results, want %d", len(results), len(answers))
+	}
+	for i, result := range results {
+		response, answered := responses[answers[i].File]
+		wantScore := 0.0
+		if answered && response != "Roughly 1,000." {
+			wantScore = 1
+		}
+		if result.Desc != answers[i].Desc || result.Missing == answered || result.Score != wantScore {
+			t.Errorf("result %d = %s (missing %v, score %.1f), want %s (missing %v, score %.1f)",
+				i, result.Desc, result.Missing, result.Score, answers[i].Desc, !answered, wantScore)
+		}
+	}
+}
@@ -0,0 +1,314 @@
+package promptgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --- Runner Configuration ---
+const (
+	RUN_REQUEST_TIMEOUT = 5 * time.Minute
+	RUN_BACKOFF_BASE    = 1 * time.Second
+	RUN_BACKOFF_MAX     = 60 * time.Second
+	RUN_API_KEY_ENV     = "LLM_API_KEY" // Sent as a Bearer token when set
+	RUN_LOG_FILENAME    = "run_log.json"
+)
+
+// RunRecord is what the runner saved for one prompt; run_log.json holds one per prompt.
+type RunRecord struct {
+	Desc         string  `json:"desc"`
+	File         string  `json:"file"`
+	ResponseFile string  `json:"response_file,omitempty"`
+	RawFile      string  `json:"raw_file,omitempty"`
+	HTTPStatus   int     `json:"http_status"` // Of the last attempt; 0 when no response arrived
+	Attempts     int     `json:"attempts"`
+	Seconds      float64 `json:"seconds"`
+	Skipped      bool    `json:"skipped,omitempty"` // The response file already existed
+	Error        string  `json:"error,omitempty"`
+}
+
+// --- Token Bucket Rate Limiter ---
+// tokenBucket allows rate requests per second on average with bursts of up to capacity. A rate of
+// 0 disables limiting.
+type tokenBucket struct {
+	mu       sync.Mutex
+	rate     float64
+	capacity float64
+	tokens   float64
+	last     time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{rate: rate, capacity: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// wait blocks until a token is available and takes it.
+func (b *tokenBucket) wait() {
+	if b.rate <= 0 {
+		return
+	}
+	for {
+		b.mu.Lock()
+		now := time.Now()
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.capacity {
+			b.tokens = b.capacity
+		}
+		b.last = now
+		if b.tokens >= 1 {
+			b.tokens--
+			b.mu.Unlock()
+			return
+		}
+		delay := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		b.mu.Unlock()
+		time.Sleep(delay)
+	}
+}
+
+// --- Functions to Call the Model ---
+type chatCompletionRequest struct {
+	Model    string        `json:"model"`
+	Messages []ChatMessage `json:"messages"`
+}
+
+type chatCompletionResponse struct {
+	Choices []struct {
+		Message ChatMessage `json:"message"`
+	} `json:"choices"`
+}
+
+// promptMessages turns a prompt file into chat messages: -output-style chat files are already a
+// message array, flat files become one user message. Any -annotate answer comment is removed.
+func promptMessages(file string, content []byte) ([]ChatMessage, error) {
+	if strings.HasSuffix(strings.TrimSuffix(file, ".gz"), ".json") {
+		var messages []ChatMessage
+		if err := json.Unmarshal(content, &messages); err != nil {
+			return nil, fmt.Errorf("decoding chat messages in %s: %w", file, err)
+		}
+		for i := range messages {
+			messages[i].Content = stripAnswerAnnotation(messages[i].Content)
+		}
+		return messages, nil
+	}
+	return []ChatMessage{{Role: "user", Content: stripAnswerAnnotation(string(content))}}, nil
+}
+
+// retryable reports whether an HTTP status is worth retrying: rate limiting and server errors.
+func retryable(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+// retryDelay honours a Retry-After header (seconds or an HTTP date) and otherwise backs off
+// exponentially from RUN_BACKOFF_BASE, with jitter. Either way the wait is at most RUN_BACKOFF_MAX.
+func retryDelay(retryAfter string, attempt int) time.Duration {
+	if retryAfter != "" {
+		if seconds, err := strconv.Atoi(strings.TrimSpace(retryAfter)); err == nil && seconds >= 0 {
+			if seconds > int(RUN_BACKOFF_MAX/time.Second) {
+				return RUN_BACKOFF_MAX
+			}
+			return time.Duration(seconds) * time.Second
+		}
+		if when, err := http.ParseTime(retryAfter); err == nil {
+			if delay := time.Until(when); delay > RUN_BACKOFF_MAX {
+				return RUN_BACKOFF_MAX
+			} else if delay > 0 {
+				return delay
+			}
+			return 0
+		}
+	}
+	delay := RUN_BACKOFF_BASE << uint(attempt)
+	if delay <= 0 || delay > RUN_BACKOFF_MAX {
+		delay = RUN_BACKOFF_MAX
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// callModel posts messages to endpoint, retrying retryable statuses and transport errors up to
+// maxRetries times. It returns the last response body and status along with the attempt count.
+func callModel(client *http.Client, limiter *tokenBucket, endpoint, model, desc string, messages []ChatMessage, maxRetries int) ([]byte, int, int, error) {
+	payload, err := json.Marshal(chatCompletionRequest{Model: model, Messages: messages})
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("encoding request: %w", err)
+	}
+	apiKey := os.Getenv(RUN_API_KEY_ENV)
+	var body []byte
+	status := 0
+	for attempt := 0; ; attempt++ {
+		limiter.wait()
+		request, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(payload))
+		if err != nil {
+			return nil, 0, attempt + 1, fmt.Errorf("building request: %w", err)
+		}
+		request.Header.Set("Content-Type", "application/json")
+		if apiKey != "" {
+			request.Header.Set("Authorization", "Bearer "+apiKey)
+		}
+		retryAfter := ""
+		resp, err := client.Do(request)
+		if err == nil {
+			status = resp.StatusCode
+			retryAfter = resp.Header.Get("Retry-After")
+			body, err = io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err == nil && !retryable(status) {
+				if status != http.StatusOK {
+					return body, status, attempt + 1, fmt.Errorf("endpoint returned %s", resp.Status)
+				}
+				return body, status, attempt + 1, nil
+			}
+			if err == nil {
+				err = fmt.Errorf("endpoint returned %s", resp.Status)
+			}
+		}
+		if attempt >= maxRetries {
+			return body, status, attempt + 1, fmt.Errorf("giving up after %d attempts: %w", attempt+1, err)
+		}
+		delay := retryDelay(retryAfter, attempt)
+		log.Printf("Warning: %s attempt %d failed: %v. Retrying in %s.", desc, attempt+1, err, delay.Round(time.Millisecond))
+		time.Sleep(delay)
+	}
+}
+
+// --- Function to Run Every Prompt Against the Model ---
+// runPrompts sends each prompt in promptDir's answer key to endpoint on a pool of workers sharing
+// one rate limiter, and saves response_<desc>.txt (the reply text, ready for -grade) plus
+// response_<desc>.raw.json (the raw HTTP body) in responseDir. Existing non-empty response files
+// are kept unless force is set, so an interrupted run can resume.
+func runPrompts(promptDir, responseDir, endpoint, model string, workers int, limiter *tokenBucket, maxRetries int, force bool) ([]RunRecord, error) {
+	answers, err := loadAnswerKey(promptDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(responseDir, 0755); err != nil {
+		return nil, fmt.Errorf("creating %s: %w", responseDir, err)
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	// Prompts kept from an earlier run keep that run's log entry.
+	previous := make(map[string]RunRecord)
+	if content, err := os.ReadFile(filepath.Join(responseDir, RUN_LOG_FILENAME)); err == nil {
+		var earlier []RunRecord
+		if err := json.Unmarshal(content, &earlier); err == nil {
+			for _, record := range earlier {
+				previous[record.File] = record
+			}
+		}
+	}
+	client := &http.Client{Timeout: RUN_REQUEST_TIMEOUT}
+	records := make([]RunRecord, len(answers))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				records[i] = runOnePrompt(client, limiter, promptDir, responseDir, endpoint, model, answers[i], maxRetries, force)
+			}
+		}()
+	}
+	for i := range answers {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	for i, record := range records {
+		if earlier, ok := previous[record.File]; ok && record.Skipped {
+			earlier.Skipped = true
+			records[i] = earlier
+		}
+	}
+
+	content, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return records, fmt.Errorf("encoding run log: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(responseDir, RUN_LOG_FILENAME), content, 0644); err != nil {
+		return records, fmt.Errorf("writing run log: %w", err)
+	}
+	return records, nil
+}
+
+// RunPrompts sends every prompt in opts.RunDir to opts.RunEndpoint (see runPrompts), warns about
+// each one that failed and prints a one-line tally.
+func RunPrompts(opts Options) error {
+	out := opts.out()
+	records, err := runPrompts(opts.RunDir, opts.ResponsesDir, opts.RunEndpoint, opts.RunModel, opts.Concurrency, newTokenBucket(opts.RateLimit, opts.RateBurst), opts.MaxRetries, opts.Force)
+	if err != nil {
+		return fmt.Errorf("running prompts: %w", err)
+	}
+	failed, skipped := 0, 0
+	for _, record := range records {
+		switch {
+		case record.Skipped:
+			skipped++
+		case record.Error != "":
+			failed++
+			log.Printf("Warning: %s failed (HTTP %d after %d attempts): %s", record.Desc, record.HTTPStatus, record.Attempts, record.Error)
+		}
+	}
+	fmt.Fprintf(out, "Ran %d prompts: %d answered, %d kept from an earlier run, %d failed. Responses are in '%s'.\n",
+		len(records), len(records)-skipped-failed, skipped, failed, opts.ResponsesDir)
+	return nil
+}
+
+func runOnePrompt(client *http.Client, limiter *tokenBucket, promptDir, responseDir, endpoint, model string, answer PromptAnswer, maxRetries int, force bool) (record RunRecord) {
+	record = RunRecord{Desc: answer.Desc, File: answer.File, ResponseFile: responseFileFor(answer.File)}
+	responsePath := filepath.Join(responseDir, record.ResponseFile)
+	if info, err := os.Stat(responsePath); err == nil && info.Size() > 0 && !force {
+		record.Skipped = true
+		return record
+	}
+	start := time.Now()
+	defer func() { record.Seconds = time.Since(start).Seconds() }()
+
+	content, err := readOutputFile(filepath.Join(promptDir, strings.TrimSuffix(answer.File, ".gz")))
+	if err != nil {
+		record.Error = fmt.Sprintf("reading prompt: %v", err)
+		return record
+	}
+	messages, err := promptMessages(answer.File, content)
+	if err != nil {
+		record.Error = err.Error()
+		return record
+	}
+	body, status, attempts, callErr := callModel(client, limiter, endpoint, model, answer.Desc, messages, maxRetries)
+	record.HTTPStatus, record.Attempts = status, attempts
+	if body != nil {
+		record.RawFile = strings.TrimSuffix(record.ResponseFile, ".txt") + ".raw.json"
+		if err := os.WriteFile(filepath.Join(responseDir, record.RawFile), body, 0644); err != nil {
+			record.Error = fmt.Sprintf("writing raw response: %v", err)
+			return record
+		}
+	}
+	if callErr != nil {
+		record.Error = callErr.Error()
+		return record
+	}
+	var completion chatCompletionResponse
+	if err := json.Unmarshal(body, &completion); err != nil || len(completion.Choices) == 0 {
+		record.Error = "response has no choices[0].message.content"
+		return record
+	}
+	if err := os.WriteFile(responsePath, []byte(completion.Choices[0].Message.Content), 0644); err != nil {
+		record.Error = fmt.Sprintf("writing response: %v", err)
+	}
+	return record
+}
@@ -0,0 +1,124 @@
+package promptgen
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRetryDelayIsCapped(t *testing.T) {
+	tests := []struct {
+		name       string
+		retryAfter string
+		attempt    int
+		want       time.Duration // 0 checks only the cap
+	}{
+		{name: "seconds", retryAfter: "5", want: 5 * time.Second},
+		{name: "huge seconds", retryAfter: "86400", want: RUN_BACKOFF_MAX},
+		{name: "overflowing seconds", retryAfter: "9223372036854775807", want: RUN_BACKOFF_MAX},
+		{name: "far HTTP date", retryAfter: time.Now().Add(24 * time.Hour).UTC().Format(http.TimeFormat), want: RUN_BACKOFF_MAX},
+		{name: "backoff", attempt: 40},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := retryDelay(tt.retryAfter, tt.attempt)
+			if got > RUN_BACKOFF_MAX {
+				t.Errorf("retryDelay = %v, above RUN_BACKOFF_MAX %v", got, RUN_BACKOFF_MAX)
+			}
+			if tt.want != 0 && got != tt.want {
+				t.Errorf("retryDelay = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunPrompts(t *testing.T) {
+	t.Setenv(RUN_API_KEY_ENV, "test-key")
+	promptDir, responseDir := t.TempDir(), t.TempDir()
+	answers := []PromptAnswer{
+		{Desc: "01_rate_limited", File: "prompt_01_rate_limited.txt"},
+		{Desc: "02_rejected", File: "prompt_02_rejected.txt"},
+		{Desc: "03_answered_earlier", File: "prompt_03_answered_earlier.txt"},
+	}
+	for _, answer := range answers {
+		if err := os.WriteFile(filepath.Join(promptDir, answer.File), []byte(answer.Desc), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := newBareGenerator(t).writeAnswerKey(promptDir, answers, "json"); err != nil {
+		t.Fatalf("writeAnswerKey: %v", err)
+	}
+	kept := filepath.Join(responseDir, responseFileFor(answers[2].File))
+	if err := os.WriteFile(kept, []byte("earlier reply"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	requests := make(map[string]int) // By prompt text, which is the prompt's desc
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request chatCompletionRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil || len(request.Messages) != 1 {
+			http.Error(w, "bad request body", http.StatusBadRequest)
+			return
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer test-key" {
+			t.Errorf("Authorization = %q, want the $%s bearer token", got, RUN_API_KEY_ENV)
+		}
+		desc := request.Messages[0].Content
+		mu.Lock()
+		requests[desc]++
+		attempt := requests[desc]
+		mu.Unlock()
+		switch {
+		case desc == "01_rate_limited" && attempt == 1:
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, "slow down", http.StatusTooManyRequests)
+		case desc == "02_rejected":
+			http.Error(w, "no such model", http.StatusNotFound)
+		default:
+			fmt.Fprintf(w, `{"choices": [{"message": {"role": "assistant", "content": "reply to %s"}}]}`, desc)
+		}
+	}))
+	defer server.Close()
+
+	records, err := runPrompts(promptDir, responseDir, server.URL, "test-model", 2, newTokenBucket(0, 1), 3, false)
+	if err != nil {
+		t.Fatalf("runPrompts: %v", err)
+	}
+	if len(records) != len(answers) {
+		t.Fatalf("got %d records, want %d", len(records), len(answers))
+	}
+
+	if record := records[0]; record.Attempts != 2 || record.HTTPStatus != http.StatusOK || record.Error != "" {
+		t.Errorf("rate-limited prompt: %+v, want 2 attempts ending in 200", record)
+	}
+	if got, err := os.ReadFile(filepath.Join(responseDir, records[0].ResponseFile)); err != nil || string(got) != "reply to 01_rate_limited" {
+		t.Errorf("saved response = %q, %v; want the reply text", got, err)
+	}
+	if _, err := os.Stat(filepath.Join(responseDir, records[0].RawFile)); err != nil {
+		t.Errorf("raw response not saved: %v", err)
+	}
+
+	if record := records[1]; record.Attempts != 1 || record.HTTPStatus != http.StatusNotFound || record.Error == "" {
+		t.Errorf("rejected prompt: %+v, want one attempt failing with 404", record)
+	}
+	if _, err := os.Stat(filepath.Join(responseDir, records[1].ResponseFile)); !os.IsNotExist(err) {
+		t.Errorf("rejected prompt left a response file (stat err %v)", err)
+	}
+
+	if !records[2].Skipped || requests["03_answered_earlier"] != 0 {
+		t.Errorf("answered prompt: %+v sent %d times, want it skipped", records[2], requests["03_answered_earlier"])
+	}
+	if got, err := os.ReadFile(kept); err != nil || string(got) != "earlier reply" {
+		t.Errorf("kept response = %q, %v; want it unchanged", got, err)
+	}
+	if _, err := os.Stat(filepath.Join(responseDir, RUN_LOG_FILENAME)); err != nil {
+		t.Errorf("run log not written: %v", err)
+	}
+}
@@ -0,0 +1,231 @@
+package promptgen
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/go-faker/faker/v4"
+)
+
+// --- Entity Schemas ---
+// Schema describes the four columns of a generated record and how to fill them. PersonEntry
+// stores records of every theme: Name is the record's identifier, Age its number, City its place
+// and JobTitle its category, each rendered under the schema's labels. The prompt templates are
+// written about people; other schemas reword the English question text with Terms.
+type Schema struct {
+	Theme         string // -theme value
+	Noun          string // Singular noun used in progress output
+	NameLabel     string
+	NumberLabel   string
+	PlaceLabel    string
+	CategoryLabel string
+	NumberMin     int
+	NumberMax     int
+	Categories    []string
+	NewName       func(rng randomSource) (string, error)
+	NewPlaces     func() ([]string, error) // nil means the cities fetched from the API
+	Terms         []string                 // old, new pairs; whole words, earlier pairs win
+	termPattern   *regexp.Regexp
+	termMap       map[string]string
+}
+
+var peopleSchema = &Schema{
+	Theme: "people", Noun: "person",
+	NameLabel: "Name", NumberLabel: "Age", PlaceLabel: "City", CategoryLabel: "Job Title",
+	NumberMin: MIN_AGE, NumberMax: MAX_AGE,
+	Categories: predefinedJobTitles,
+	NewName:    func(randomSource) (string, error) { return fakerPersonName() },
+}
+
+var productsSchema = &Schema{
+	Theme: "products", Noun: "product",
+	NameLabel: "Name", NumberLabel: "Price", PlaceLabel: "Warehouse", CategoryLabel: "Category",
+	NumberMin: 5, NumberMax: 500,
+	Categories: []string{"Kitchenware", "Garden", "Electronics", "Toys", "Stationery", "Sports", "Lighting",
+		"Bathroom", "Tools", "Pet Supplies", "Outdoor", "Office Furniture", "Bedding", "Automotive", "Craft Supplies"},
+	NewName: func(rng randomSource) (string, error) {
+		return pick(rng, productAdjectives) + " " + pick(rng, productMaterials) + " " + pick(rng, productNouns), nil
+	},
+	NewPlaces: func() ([]string, error) { return append([]string{}, warehouseNames...), nil },
+	Terms: []string{
+		"distinct cities do they live in", "distinct warehouses are they stocked in",
+		"how many people live there", "how many products are stocked there",
+		"how many people hold it", "how many products are in it",
+		"people living in", "products stocked in",
+		"hold each", "are in each",
+		"job titles", "categories", "job title", "category", "Job Title", "Category",
+		"cities", "warehouses", "city", "warehouse", "City", "Warehouse",
+		"lives in", "is stocked in", "live in", "are stocked in",
+		"an Age", "a Price", "an age", "a price", "ages", "prices", "age", "price", "Ages", "Prices", "Age", "Price",
+		"people", "products", "People", "Products", "person's", "product's", "person", "product",
+		"If so, who", "If so, which", "who has", "which has", "Who", "Which products", "who", "that",
+		"everyone", "every product", "full names", "names",
+	},
+}
+
+var booksSchema = &Schema{
+	Theme: "books", Noun: "book",
+	NameLabel: "Title", NumberLabel: "Year", PlaceLabel: "Author", CategoryLabel: "Genre",
+	NumberMin: 1900, NumberMax: 2024,
+	Categories: []string{"Mystery", "Science Fiction", "Fantasy", "Romance", "Biography", "History", "Poetry",
+		"Thriller", "Travel", "Cookery", "Philosophy", "Horror", "Humour", "Essays", "Drama"},
+	NewName: func(rng randomSource) (string, error) {
+		if rng.Intn(2) == 0 {
+			return "The " + pick(rng, titleAdjectives) + " " + pick(rng, titleNouns), nil
+		}
+		return pick(rng, titleNouns) + " of the " + pick(rng, titleAdjectives) + " " + pick(rng, titleNouns), nil
+	},
+	NewPlaces: func() ([]string, error) {
+		authors := []string{}
+		seen := make(map[string]bool)
+		for attempts := 0; len(authors) < BOOK_AUTHOR_COUNT && attempts < BOOK_AUTHOR_COUNT*10; attempts++ {
+			name, err := fakerPersonName()
+			if err != nil {
+				return nil, fmt.Errorf("generating author: %w", err)
+			}
+			if !seen[name] {
+				seen[name] = true
+				authors = append(authors, name)
+			}
+		}
+		return authors, nil
+	},
+	Terms: []string{
+		"distinct cities do they live in", "distinct authors wrote them",
+		"how many people live there", "how many books they wrote",
+		"how many people hold it", "how many books are in it",
+		"people living in", "books written by",
+		"hold each", "are in each",
+		"job titles", "genres", "job title", "genre", "Job Title", "Genre",
+		"cities", "authors", "city", "author", "City", "Author",
+		"lives in", "was written by", "live in", "were written by",
+		"an Age", "a Year", "an age", "a year", "ages", "years", "age", "year", "Ages", "Years", "Age", "Year",
+		"people", "books", "People", "Books", "person's", "book's", "person", "book",
+		"If so, who", "If so, which", "who has", "which has", "Who", "Which books", "who", "that",
+		"everyone", "every book", "full names", "titles",
+		"names", "titles", "name", "title", "Names", "Titles", "Name", "Title",
+	},
+}
+
+var schemas = []*Schema{peopleSchema, productsSchema, booksSchema}
+
+const BOOK_AUTHOR_COUNT = 60 // Authors drawn for the books theme, playing the role of cities
+
+// Word lists for the products and books name generators; their products give well over
+// NUM_ENTRIES distinct names, so the usual collision budget is enough.
+var (
+	productAdjectives = []string{"Compact", "Deluxe", "Classic", "Portable", "Foldable", "Rustic", "Modern", "Heavy Duty",
+		"Slim", "Ergonomic", "Vintage", "Premium", "Mini", "Tall", "Round", "Square", "Insulated", "Wireless",
+		"Adjustable", "Stackable", "Waterproof", "Quiet", "Bright", "Soft", "Sturdy"}
+	productMaterials = []string{"Steel", "Oak", "Bamboo", "Ceramic", "Glass", "Copper", "Cotton", "Leather", "Wool",
+		"Aluminium", "Marble", "Walnut", "Linen", "Brass", "Silicone"}
+	productNouns = []string{"Kettle", "Lamp", "Stool", "Shelf", "Mug", "Basket", "Clock", "Vase", "Tray", "Bench",
+		"Planter", "Blanket", "Bottle", "Desk", "Mirror", "Hook", "Rack", "Bowl", "Speaker", "Cushion", "Jar",
+		"Ladder", "Bin", "Frame", "Caddy"}
+	warehouseNames = []string{"Riverside Depot", "Harbor Point", "Eastgate Yard", "Millbrook Store", "Northfield Hub",
+		"Stonebridge Depot", "Westlake Yard", "Cedar Hall", "Ironworks Depot", "Fairview Hub", "Kingsmoor Store",
+		"Ashdown Yard"}
+	titleAdjectives = []string{"Silent", "Hidden", "Last", "Golden", "Broken", "Forgotten", "Distant", "Burning", "Quiet",
+		"Crimson", "Endless", "Hollow", "Lost", "Northern", "Painted", "Secret", "Shattered", "Silver", "Sleeping",
+		"Wandering", "Winter", "Wild", "Bitter", "Gentle", "Final"}
+	titleNouns = []string{"Garden", "River", "Tower", "Letter", "Harbor", "Mirror", "Orchard", "Storm", "Lantern",
+		"Kingdom", "Island", "Bridge", "Forest", "Crown", "Shadow", "Voyage", "Promise", "Mountain", "Station",
+		"Library", "Compass", "Meadow", "Archive", "Signal", "House"}
+)
+
+func pick(rng randomSource, words []string) string {
+	return words[rng.Intn(len(words))]
+}
+
+// fakerPersonName returns a "First Last" name from faker.
+func fakerPersonName() (string, error) {
+	var nameH nameHelper
+	if err := faker.FakeData(&nameH); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s %s", nameH.FirstName, nameH.LastName), nil
+}
+
+// formatPersonName renders a "First Last" name in the -name-format style. Names of other themes
+// (and names without a space) are returned unchanged.
+func (g *Generator) formatPersonName(name string) string {
+	i := strings.LastIndex(name, " ")
+	if g.schema != peopleSchema || i < 0 {
+		return name
+	}
+	first, last := name[:i], name[i+1:]
+	switch g.opts.NameFormat {
+	case "last-first":
+		return last + ", " + first
+	case "initial-last":
+		return string([]rune(first)[:1]) + ". " + last
+	}
+	return name
+}
+
+// lastName returns the last name of a person entry, whatever -name-format it is rendered in.
+func lastName(entry PersonEntry) string {
+	name := entry.Name
+	if entry.CanonicalName != "" {
+		name = entry.CanonicalName
+	}
+	return name[strings.LastIndex(name, " ")+1:]
+}
+
+// SchemaForTheme returns the schema registered for theme.
+func SchemaForTheme(theme string) (*Schema, error) {
+	names := make([]string, len(schemas))
+	for i, schema := range schemas {
+		if schema.Theme == theme {
+			return schema, nil
+		}
+		names[i] = schema.Theme
+	}
+	return nil, fmt.Errorf("unknown theme %q (choose %s)", theme, strings.Join(names, ", "))
+}
+
+// reword applies the schema's Terms to English question text in a single pass, so a replacement
+// is never itself rewritten. Template actions such as {{.TargetJobTitle}} are not whole words and
+// are left alone.
+func (s *Schema) reword(text string) string {
+	if len(s.Terms) == 0 {
+		return text
+	}
+	if s.termPattern == nil {
+		alternatives := make([]string, 0, len(s.Terms)/2)
+		s.termMap = make(map[string]string, len(s.Terms)/2)
+		for i := 0; i+1 < len(s.Terms); i += 2 {
+			alternatives = append(alternatives, regexp.QuoteMeta(s.Terms[i]))
+			s.termMap[s.Terms[i]] = s.Terms[i+1]
+		}
+		s.termPattern = regexp.MustCompile(`\b(?:` + strings.Join(alternatives, "|") + `)\b`)
+	}
+	return s.termPattern.ReplaceAllStringFunc(text, func(match string) string { return s.termMap[match] })
+}
+
+// peopleOnly reports whether the prompt's question depends on people semantics (ages as years
+// lived, birth years, last names, job titles used as nouns) that a Terms rewording cannot carry over.
+// Other themes skip these configs; Generate lists the dropped Descs.
+func peopleOnly(config PromptConfig) bool {
+	return config.IsAgeDifference || config.IsSortedAges || config.IsJobSubstring || config.IsAbsentField || config.IsCompositeKey ||
+		config.IsMultiAgeCity || config.IsBornInYear || config.IsHardReverse || config.IsIndirectRef ||
+		config.IsTopNWithTies || config.IsAgeDescriptor || config.IsTwoSection || config.IsJSONOutput ||
+		config.IsYearsUntilAge || config.IsMarkdownTable || config.IsAgeUpdates ||
+		config.IsNestedJSON || config.IsSingletonCity || config.IsListOrder
+}
+
+// selectThemeConfigs drops the peopleOnly configs when the active schema is not people.
+func (g *Generator) selectThemeConfigs(configs []PromptConfig) (kept []PromptConfig, dropped []string) {
+	if g.schema == peopleSchema {
+		return configs, nil
+	}
+	for _, config := range configs {
+		if peopleOnly(config) {
+			dropped = append(dropped, config.Desc)
+		} else {
+			kept = append(kept, config)
+		}
+	}
+	return kept, dropped
+}
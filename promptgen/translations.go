@@ -0,0 +1,211 @@
+package promptgen
+
+// --- Question Translations ---
+// promptTranslations maps a language code to translated templates keyed by PromptConfig.Desc.
+// The data block itself stays in English; only the surrounding question is translated.
+// Field labels (Name, Age, City, Job Title) are left in English so they match the data block.
+var promptTranslations = map[string]map[string]string{
+	"es": {
+		"01_standard_retrieval_10":        `Aquí está la lista:\n{{.DataBlock}}\n\nSegún la lista anterior, ¿cuáles son las edades de:\n{{.QueryItemsFormatted}}`,
+		"02_different_phrasing_10":        `Observa los siguientes datos:\n{{.DataBlock}}\n\nUsando solo estos datos, encuentra las edades asociadas a estos nombres: {{.QueryItemsFormattedInline}}.`,
+		"03_fewer_items_5":                `Datos:\n{{.DataBlock}}\n\nIndica las edades de:\n{{.QueryItemsFormatted}}`,
+		"04_more_items_15":                `Lista:\n{{.DataBlock}}\n\nPor favor, indica las edades de las siguientes {{.QueryItemCount}} personas:\n{{.QueryItemsFormatted}}`,
+		"05_start_end_focus_2":            `Conjunto de datos:\n{{.DataBlock}}\n\n¿Cuál es la edad de {{.QueryName1}} y la edad de {{.QueryName2}} según este conjunto de datos?`,
+		"06_reverse_lookup_name":          `Nombres y edades:\n{{.DataBlock}}\n\nSegún la lista, ¿qué persona tiene {{.QueryAge1}} años? ¿Y quién tiene {{.QueryAge2}} años? (Si las edades no son únicas, enumera todos los nombres encontrados)`,
+		"07_combined_request":             `Datos de referencia:\n{{.DataBlock}}\n\nEncuentra la edad de {{.QueryName1}}. Además, encuentra la edad de {{.QueryName2}}. Por último, encuentra el nombre asociado a la edad {{.QueryAge3}}.`,
+		"08_sequential_names_5":           `Registro de datos:\n{{.DataBlock}}\n\n¿Cuáles son las edades de {{.QueryName1}}, {{.QueryName2}}, {{.QueryName3}}, {{.QueryName4}} y {{.QueryName5}}?`,
+		"09_widely_spaced_names_10":       `Lista de personas:\n{{.DataBlock}}\n\nExtrae las edades de: {{.QueryItemsFormattedInline}}.`,
+		"10_retrieval_confirmation":       `Lista maestra:\n{{.DataBlock}}\n\nIndica las edades de {{.QueryItemsFormattedInline}}. Además, confirma si '{{.NonExistentName}}' está presente en esta lista.`,
+		"11_filter_city_get_name_job":     `Detalle de la lista:\n{{.DataBlock}}\n\nEnumera los nombres y puestos de trabajo (Job Title) de todas las personas de la lista que viven en la ciudad '{{.TargetCity}}'.`,
+		"12_filter_job_get_name_age":      `Datos de empleados:\n{{.DataBlock}}\n\nEncuentra los nombres y las edades de todas las personas con el puesto de trabajo '{{.TargetJobTitle}}'.`,
+		"13_filter_age_city_get_name":     `Información de residentes:\n{{.DataBlock}}\n\n¿Quién de la lista tiene entre {{.MinAge}} y {{.MaxAge}} años Y vive en '{{.TargetCity}}'? Enumera sus nombres completos.`,
+		"14_count_job_city":               `Datos del censo:\n{{.DataBlock}}\n\n¿Cuántas personas de la lista tienen el puesto de trabajo '{{.TargetJobTitle}}' Y viven en la ciudad '{{.TargetCity}}'? Indica solo el número.`,
+		"15_filter_job_retrieve_all":      `Expedientes del personal:\n{{.DataBlock}}\n\nProporciona todos los datos disponibles (Name, Age, City, Job Title) de todas las personas cuyo puesto de trabajo es '{{.TargetJobTitle}}'.`,
+		"16_sum_ages_3":                   `Lista de miembros:\n{{.DataBlock}}\n\n¿Cuál es la suma de las edades de {{.QueryName1}}, {{.QueryName2}} y {{.QueryName3}}? Indica el total.`,
+		"17_age_difference_2":             `Registro:\n{{.DataBlock}}\n\n¿Cuántos años mayor es {{.QueryName1}} que {{.QueryName2}}?`,
+		"18_unknown_city":                 `Registros de contacto (una City de '-' significa desconocida):\n{{.DataBlock}}\n\nEnumera los nombres completos de todas las personas cuya ciudad es desconocida.`,
+		"19_sorted_ages_10":               `Nómina:\n{{.DataBlock}}\n\nEnumera las edades de las siguientes {{.QueryItemCount}} personas, ordenadas de la más joven a la mayor:\n{{.QueryItemsFormatted}}`,
+		"20_filter_job_substring":         `Directorio del personal:\n{{.DataBlock}}\n\nEnumera los nombres y puestos de trabajo de todas las personas cuyo puesto de trabajo contiene la palabra '{{.TargetKeyword}}'.`,
+		"21_absent_field_favorite_color":  `Perfiles de miembros:\n{{.DataBlock}}\n\n¿Cuál es el color favorito de {{.QueryName1}}?`,
+		"22_presence_check_mixed":         `Directorio de miembros:\n{{.DataBlock}}\n\nPara cada uno de los siguientes nombres, indica si la persona está presente o ausente en la lista anterior:\n{{.QueryItemsFormatted}}`,
+		"23_composite_key_job_city":       `Padrón municipal:\n{{.DataBlock}}\n\n{{if .Unique}}¿Cuáles son el nombre y la edad de la persona con el puesto '{{.TargetJobTitle}}' que vive en {{.TargetCity}}?{{else}}Enumera los nombres y las edades de todas las personas con el puesto '{{.TargetJobTitle}}' que viven en {{.TargetCity}}.{{end}}`,
+		"24_varying_delimiters_10":        `Registros combinados (las filas proceden de fuentes distintas):\n{{.DataBlock}}\n\n¿Cuáles son las edades de las siguientes personas?\n{{.QueryItemsFormatted}}`,
+		"25_json_output_ages_10":          `Registros de miembros:\n{{.DataBlock}}\n\nEncuentra las edades de las siguientes personas:\n{{.QueryItemsFormatted}}\n\nResponde solo con un objeto JSON de la forma {"answers": [{"name": "<nombre completo>", "age": <entero>}]}, con un elemento por persona.`,
+		"26_city_job_breakdown":           `Datos del censo:\n{{.DataBlock}}\n\nPara las personas que viven en {{.TargetCity}}, ¿cuántas tienen cada puesto de trabajo? Proporciona un desglose con cada puesto y su recuento.`,
+		"27_duplicate_detection":          `Lista de asistentes:\n{{.DataBlock}}\n\n¿Hay personas que aparezcan más de una vez? Si es así, ¿quiénes? Indica el nombre completo de cada persona que aparece más de una vez, o responde 'none'.`,
+		"28_two_section_scoped":           `Registros de la empresa (dos listas separadas):\n{{.DataBlock}}\n\nAlgunos nombres aparecen en ambas listas con datos distintos. Usando solo la lista {{.TargetSection}}, ¿qué edades tienen las siguientes personas?\n{{.QueryItemsFormatted}}`,
+		"29_split_attribute_join_5":       `Registros de miembros (una edad '-' aparece en una línea 'Age index' separada en otra parte de la lista):\n{{.DataBlock}}\n\n¿Qué edades tienen las siguientes personas?\n{{.QueryItemsFormatted}}`,
+		"30_age_threshold_comparison":     `Datos del censo:\n{{.DataBlock}}\n\n¿Hay en la lista más personas mayores de {{.OverAge}} que menores de {{.UnderAge}}? Da ambos recuentos y luego responde sí o no.`,
+		"31_corrupted_entry_lookup":       `Registros de miembros (reproduce los valores tal como aparecen):\n{{.DataBlock}}\n\n¿Qué edad y qué ciudad tiene cada una de las siguientes personas, exactamente como figuran en la lista?\n{{.QueryItemsFormatted}}`,
+		"32_most_common_city":             `Datos del censo:\n{{.DataBlock}}\n\n¿Qué ciudad aparece con más frecuencia en la lista? Indica la ciudad y cuántas personas viven en ella.`,
+		"33_most_common_job":              `Datos del censo:\n{{.DataBlock}}\n\n¿Cuál es el puesto de trabajo más común en la lista? Indica el puesto y cuántas personas lo ocupan.`,
+		"34_born_in_year":                 `Datos del censo:\n{{.DataBlock}}\n\n¿Quién nació en {{.TargetBirthYear}}? Indica el nombre completo de todas las personas nacidas ese año.`,
+		"35_hard_reverse_lookup_all":      `Nombres y edades:\n{{.DataBlock}}\n\nEnumera a todas las personas de la lista que tienen exactamente {{.QueryAge1}} años. Varias comparten puesto de trabajo o apellido, así que revisa cada fila; inclúyelas a todas e indica cuántas son.`,
+		"36_line_number_lookup":           `Registros numerados:\n{{.DataBlock}}\n\n¿Qué hay en la línea {{.LineNumber}}? Indica el nombre, la edad, la ciudad y el puesto de trabajo que figuran en ella.`,
+		"37_prompt_injection_decoy_5":     `Registros de miembros:\n{{.DataBlock}}\n\n¿Qué edades tienen las siguientes personas según los registros?\n{{.QueryItemsFormatted}}`,
+		"38_indirect_reference_city":      `Directorio del personal:\n{{.DataBlock}}\n\nPiensa en la persona {{if .Oldest}}de mayor edad{{else}}más joven{{end}} con el puesto {{.TargetJobTitle}} de la lista. ¿En qué ciudad vive? Indica su nombre y su ciudad.`,
+		"39_many_needles":                 `Registros de miembros:\n{{.DataBlock}}\n\nIndica la edad de cada una de estas {{.QueryItemCount}} personas, con una línea "Nombre: edad" por persona: {{.QueryItemsFormattedInline}}`,
+		"40_distinct_cities_for_job":      `Datos del censo:\n{{.DataBlock}}\n\nEntre las personas con el puesto de trabajo '{{.TargetJobTitle}}', ¿en cuántas ciudades distintas viven? Indica el número y enumera las ciudades.`,
+		"41_top_5_oldest_with_ties":       `Directorio del personal:\n{{.DataBlock}}\n\nEntre las personas con el puesto de trabajo '{{.TargetJobTitle}}', enumera las {{.TopN}} de mayor edad, de la mayor a la menor, con la edad de cada una. Si varias personas empatan con la que ocupa el puesto {{.TopN}}, inclúyelas a todas, aunque la lista tenga más de {{.TopN}} personas.`,
+		"42_qualitative_age_band":         `Directorio del personal:\n{{.DataBlock}}\n\nEnumera a todas las personas con el puesto de trabajo '{{.TargetJobTitle}}' que sean {{index .AgeDescriptor "es"}} (entre {{.MinAge}} y {{.MaxAge}} años, ambos incluidos). Indica sus nombres completos.`,
+		"43_join_city_region_table":       `Directorio del personal:\n{{.DataBlock}}\n\nTabla de regiones:\n{{.RegionTable}}\n\nUsando la tabla de regiones para encontrar la región de la ciudad de cada persona, ¿qué región tiene más personas? Indica la región y cuántas personas tiene.`,
+		"44_mixed_ages_with_absent":       `Directorio de miembros:\n{{.DataBlock}}\n\nIndica la edad de cada una de las siguientes personas, una línea "Nombre: edad" por persona, en el orden indicado. Puede que algunas no estén en la lista anterior; para ellas, responde "{{.AbsentAnswer}}" en lugar de una edad.\n{{.QueryItemsFormatted}}`,
+		"45_mixed_script_cities":          `Directorio del personal (algunos nombres de ciudades están escritos en el idioma o la escritura local):\n{{.DataBlock}}\n\nEnumera los nombres completos de todas las personas con el puesto "{{.TargetJobTitle}}" que viven en {{.TargetCity}}, se escriba como se escriba el nombre de la ciudad.`,
+		"46_years_until_milestone_age":    `Registro:\n{{.DataBlock}}\n\n¿Cuántos años faltan para que {{.QueryName1}} cumpla {{.MilestoneAge}}? Responde 0 si ya tiene {{.MilestoneAge}} años o más.`,
+		"47_unique_three_attribute_match": `Registros de personal:\n{{.DataBlock}}\n\nExactamente una persona cumple las tres condiciones siguientes:\n- Edad: {{.TargetAge}}\n- Ciudad: {{.TargetCity}}\n- Puesto de trabajo: {{.TargetJobTitle}}\n\n¿Cuál es el nombre completo de esa persona?`,
+		"48_markdown_table_output":        `Registro de empleados:\n{{.DataBlock}}\n\nEncuentra a todas las personas con el puesto '{{.TargetJobTitle}}' que tengan entre {{.MinAge}} y {{.MaxAge}} años. Responde solo con una tabla Markdown con exactamente estas columnas: Name | Age | City. Usa una fila por persona.`,
+		"49_incremental_age_updates":      `Registros de miembros:\n{{.DataBlock}}\n\nActualizaciones (se aplican en orden; una actualización posterior sustituye cualquier valor anterior):\n{{.UpdateLog}}\n\nTras aplicar todas las actualizaciones, ¿cuál es la edad actual de cada una de estas personas?\n{{.QueryItemsFormatted}}`,
+		"50_nested_json_by_city":          `Directorio (JSON; cada clave es una ciudad y enumera a las personas que viven allí):\n{{.DataBlock}}\n\n¿Bajo qué ciudad aparece {{.QueryName1}}? Indica la ciudad, su edad y su puesto de trabajo.`,
+		"51_singleton_city":               `Registro de residentes:\n{{.DataBlock}}\n\n¿Qué ciudad de la lista tiene exactamente un residente? Si hay más de una, nombra cualquiera de ellas. Indica la ciudad y el nombre de su único residente.`,
+		"52_filter_in_list_order":         `Directorio del personal:\n{{.DataBlock}}\n\nEnumera a todas las personas con el puesto de trabajo '{{.TargetJobTitle}}' que tengan entre {{.MinAge}} y {{.MaxAge}} años, en el orden en que aparecen en la lista anterior. Indica sus nombres completos, uno por línea.`,
+		"53_country_from_records":         `Registros de miembros:\n{{.DataBlock}}\n\nSegún estos registros, ¿en qué ciudad y país vive {{.QueryName1}}? Responde según los registros, aunque no coincidan con lo que sabes de la ciudad.`,
+		"54_split_instruction_3":          `Lee atentamente la siguiente lista. Después se te preguntará por algunas de las personas que aparecen en ella y deberás responder solo a partir de la lista.\n{{.DataBlock}}\n\nAhora, ¿qué edad tienen:\n{{.QueryItemsFormatted}}`,
+		"55_zero_count_job_city":          `Datos del censo:\n{{.DataBlock}}\n\n¿Cuántas personas de la lista tienen el puesto de trabajo '{{.TargetJobTitle}}' Y viven en la ciudad '{{.TargetCity}}'? Indica solo el número.`,
+		"56_job_count_difference":         `Lista de personal:\n{{.DataBlock}}\n\n¿Cuántas personas más tienen el puesto de trabajo '{{.TargetJobTitle}}' que el puesto de trabajo '{{.TargetJobTitle2}}'? Indica ambos recuentos y, después, el número de '{{.TargetJobTitle}}' menos el número de '{{.TargetJobTitle2}}' (un número negativo si hay menos).`,
+		"57_homonym_city_counts":          `Registros de miembros:\n{{.DataBlock}}\n\nEn estos registros hay dos lugares distintos llamados {{.TargetCity}}: uno en {{.TargetCountry}} y otro en {{.TargetCountry2}}. ¿Cuántas personas viven en {{.TargetCity}}, {{.TargetCountry}}, y cuántas viven en {{.TargetCity}}, {{.TargetCountry2}}? Indica ambos recuentos.`,
+	},
+	"de": {
+		"01_standard_retrieval_10":        `Hier ist die Liste:\n{{.DataBlock}}\n\nWie alt sind laut der obigen Liste:\n{{.QueryItemsFormatted}}`,
+		"02_different_phrasing_10":        `Sieh dir die folgenden Daten an:\n{{.DataBlock}}\n\nFinde ausschließlich anhand dieser Daten das Alter zu diesen Namen: {{.QueryItemsFormattedInline}}.`,
+		"03_fewer_items_5":                `Daten:\n{{.DataBlock}}\n\nNenne das Alter von:\n{{.QueryItemsFormatted}}`,
+		"04_more_items_15":                `Liste:\n{{.DataBlock}}\n\nBitte nenne das Alter der folgenden {{.QueryItemCount}} Personen:\n{{.QueryItemsFormatted}}`,
+		"05_start_end_focus_2":            `Datensatz:\n{{.DataBlock}}\n\nWie alt sind laut diesem Datensatz {{.QueryName1}} und {{.QueryName2}}?`,
+		"06_reverse_lookup_name":          `Namen und Alter:\n{{.DataBlock}}\n\nWelche Person ist laut der Liste {{.QueryAge1}} Jahre alt? Und wer ist {{.QueryAge2}} Jahre alt? (Falls das Alter nicht eindeutig ist, nenne alle gefundenen Namen)`,
+		"07_combined_request":             `Referenzdaten:\n{{.DataBlock}}\n\nFinde das Alter von {{.QueryName1}}. Finde außerdem das Alter von {{.QueryName2}}. Finde schließlich den Namen, der zum Alter {{.QueryAge3}} gehört.`,
+		"08_sequential_names_5":           `Datenprotokoll:\n{{.DataBlock}}\n\nWie alt sind {{.QueryName1}}, {{.QueryName2}}, {{.QueryName3}}, {{.QueryName4}} und {{.QueryName5}}?`,
+		"09_widely_spaced_names_10":       `Personenliste:\n{{.DataBlock}}\n\nEntnimm das Alter für: {{.QueryItemsFormattedInline}}.`,
+		"10_retrieval_confirmation":       `Stammliste:\n{{.DataBlock}}\n\nNenne das Alter von {{.QueryItemsFormattedInline}}. Bestätige außerdem, ob '{{.NonExistentName}}' in dieser Liste vorkommt.`,
+		"11_filter_city_get_name_job":     `Listendetails:\n{{.DataBlock}}\n\nNenne die Namen und Berufsbezeichnungen (Job Title) aller Personen in der Liste, die in der Stadt '{{.TargetCity}}' leben.`,
+		"12_filter_job_get_name_age":      `Mitarbeiterdaten:\n{{.DataBlock}}\n\nFinde die Namen und das Alter aller Personen mit der Berufsbezeichnung '{{.TargetJobTitle}}'.`,
+		"13_filter_age_city_get_name":     `Einwohnerinformationen:\n{{.DataBlock}}\n\nWer in der Liste ist zwischen {{.MinAge}} und {{.MaxAge}} Jahre alt UND lebt in '{{.TargetCity}}'? Nenne die vollständigen Namen.`,
+		"14_count_job_city":               `Zensusdaten:\n{{.DataBlock}}\n\nWie viele Personen in der Liste haben die Berufsbezeichnung '{{.TargetJobTitle}}' UND leben in der Stadt '{{.TargetCity}}'? Nenne nur die Anzahl.`,
+		"15_filter_job_retrieve_all":      `Personalakten:\n{{.DataBlock}}\n\nNenne alle verfügbaren Angaben (Name, Age, City, Job Title) zu allen Personen mit der Berufsbezeichnung '{{.TargetJobTitle}}'.`,
+		"16_sum_ages_3":                   `Mitgliederliste:\n{{.DataBlock}}\n\nWie hoch ist das Gesamtalter von {{.QueryName1}}, {{.QueryName2}} und {{.QueryName3}} zusammen? Nenne die Summe.`,
+		"17_age_difference_2":             `Register:\n{{.DataBlock}}\n\nUm wie viele Jahre ist {{.QueryName1}} älter als {{.QueryName2}}?`,
+		"18_unknown_city":                 `Kontaktdaten (eine City von '-' bedeutet unbekannt):\n{{.DataBlock}}\n\nNenne die vollständigen Namen aller Personen, deren Stadt unbekannt ist.`,
+		"19_sorted_ages_10":               `Dienstplan:\n{{.DataBlock}}\n\nNenne das Alter der folgenden {{.QueryItemCount}} Personen, sortiert von der jüngsten zur ältesten:\n{{.QueryItemsFormatted}}`,
+		"20_filter_job_substring":         `Mitarbeiterverzeichnis:\n{{.DataBlock}}\n\nNenne die Namen und Berufsbezeichnungen aller Personen, deren Berufsbezeichnung das Wort '{{.TargetKeyword}}' enthält.`,
+		"21_absent_field_favorite_color":  `Mitgliederprofile:\n{{.DataBlock}}\n\nWas ist die Lieblingsfarbe von {{.QueryName1}}?`,
+		"22_presence_check_mixed":         `Mitgliederverzeichnis:\n{{.DataBlock}}\n\nGib für jeden der folgenden Namen an, ob die Person in der obigen Liste vorhanden oder nicht vorhanden ist:\n{{.QueryItemsFormatted}}`,
+		"23_composite_key_job_city":       `Einwohnerregister:\n{{.DataBlock}}\n\n{{if .Unique}}Wie heißt die Person mit der Berufsbezeichnung '{{.TargetJobTitle}}', die in {{.TargetCity}} lebt, und wie alt ist sie?{{else}}Nenne die Namen und das Alter aller Personen mit der Berufsbezeichnung '{{.TargetJobTitle}}', die in {{.TargetCity}} leben.{{end}}`,
+		"24_varying_delimiters_10":        `Zusammengeführte Datensätze (die Zeilen stammen aus verschiedenen Quellen):\n{{.DataBlock}}\n\nWie alt sind die folgenden Personen?\n{{.QueryItemsFormatted}}`,
+		"25_json_output_ages_10":          `Mitgliederdaten:\n{{.DataBlock}}\n\nFinde das Alter der folgenden Personen:\n{{.QueryItemsFormatted}}\n\nAntworte ausschließlich mit einem JSON-Objekt der Form {"answers": [{"name": "<vollständiger Name>", "age": <Ganzzahl>}]}, mit einem Element pro Person.`,
+		"26_city_job_breakdown":           `Volkszählungsdaten:\n{{.DataBlock}}\n\nWie viele der Personen, die in {{.TargetCity}} leben, haben jeweils welche Berufsbezeichnung? Gib eine Aufschlüsselung mit jeder Berufsbezeichnung und ihrer Anzahl an.`,
+		"27_duplicate_detection":          `Teilnehmerliste:\n{{.DataBlock}}\n\nGibt es Personen, die mehr als einmal aufgeführt sind? Wenn ja, wer? Nenne die vollständigen Namen aller Personen, die mehr als einmal vorkommen, oder antworte 'none'.`,
+		"28_two_section_scoped":           `Unternehmensdaten (zwei getrennte Listen):\n{{.DataBlock}}\n\nEinige Namen kommen in beiden Listen mit unterschiedlichen Angaben vor. Wie alt sind die folgenden Personen laut ausschließlich der Liste {{.TargetSection}}?\n{{.QueryItemsFormatted}}`,
+		"29_split_attribute_join_5":       `Mitgliederdaten (ein Alter '-' steht in einer separaten 'Age index'-Zeile an anderer Stelle der Liste):\n{{.DataBlock}}\n\nWie alt sind die folgenden Personen?\n{{.QueryItemsFormatted}}`,
+		"30_age_threshold_comparison":     `Volkszählungsdaten:\n{{.DataBlock}}\n\nGibt es in der Liste mehr Personen über {{.OverAge}} als unter {{.UnderAge}}? Nenne beide Anzahlen und antworte dann mit ja oder nein.`,
+		"31_corrupted_entry_lookup":       `Mitgliederdaten (gib die Werte exakt wie aufgeführt wieder):\n{{.DataBlock}}\n\nWelches Alter und welche Stadt hat jede der folgenden Personen, genau so, wie es in der Liste steht?\n{{.QueryItemsFormatted}}`,
+		"32_most_common_city":             `Volkszählungsdaten:\n{{.DataBlock}}\n\nWelche Stadt kommt in der Liste am häufigsten vor? Nenne die Stadt und wie viele Personen dort leben.`,
+		"33_most_common_job":              `Volkszählungsdaten:\n{{.DataBlock}}\n\nWelche Berufsbezeichnung ist in der Liste am häufigsten? Nenne die Berufsbezeichnung und wie viele Personen sie tragen.`,
+		"34_born_in_year":                 `Volkszählungsdaten:\n{{.DataBlock}}\n\nWer wurde {{.TargetBirthYear}} geboren? Nenne die vollständigen Namen aller Personen, die in diesem Jahr geboren wurden.`,
+		"35_hard_reverse_lookup_all":      `Namen und Alter:\n{{.DataBlock}}\n\nNenne alle Personen in der Liste, die genau {{.QueryAge1}} Jahre alt sind. Mehrere von ihnen haben dieselbe Berufsbezeichnung oder denselben Nachnamen, prüfe also jede Zeile; nenne alle und gib an, wie viele es sind.`,
+		"36_line_number_lookup":           `Nummerierte Datensätze:\n{{.DataBlock}}\n\nWas steht in Zeile {{.LineNumber}}? Nenne den dort eingetragenen Namen, das Alter, die Stadt und die Berufsbezeichnung.`,
+		"37_prompt_injection_decoy_5":     `Mitgliederdaten:\n{{.DataBlock}}\n\nWie alt sind die folgenden Personen laut den Datensätzen?\n{{.QueryItemsFormatted}}`,
+		"38_indirect_reference_city":      `Mitarbeiterverzeichnis:\n{{.DataBlock}}\n\nDenke an die {{if .Oldest}}älteste{{else}}jüngste{{end}} Person mit der Berufsbezeichnung {{.TargetJobTitle}} in der Liste. In welcher Stadt lebt sie? Nenne ihren Namen und ihre Stadt.`,
+		"39_many_needles":                 `Mitgliederdaten:\n{{.DataBlock}}\n\nGib das Alter jeder dieser {{.QueryItemCount}} Personen an, eine Zeile "Name: Alter" pro Person: {{.QueryItemsFormattedInline}}`,
+		"40_distinct_cities_for_job":      `Volkszählungsdaten:\n{{.DataBlock}}\n\nIn wie vielen verschiedenen Städten leben die Personen mit der Berufsbezeichnung '{{.TargetJobTitle}}'? Nenne die Anzahl und liste die Städte auf.`,
+		"41_top_5_oldest_with_ties":       `Mitarbeiterverzeichnis:\n{{.DataBlock}}\n\nListe unter den Personen mit der Berufsbezeichnung '{{.TargetJobTitle}}' die {{.TopN}} ältesten auf, von der ältesten zur jüngsten, jeweils mit Alter. Wenn mehrere Personen mit der {{.TopN}}.-ältesten gleichauf liegen, nimm alle auf, auch wenn die Liste dadurch länger als {{.TopN}} wird.`,
+		"42_qualitative_age_band":         `Mitarbeiterverzeichnis:\n{{.DataBlock}}\n\nListe alle Personen mit der Berufsbezeichnung '{{.TargetJobTitle}}' auf, die {{index .AgeDescriptor "de"}} sind (zwischen {{.MinAge}} und {{.MaxAge}} Jahre alt, einschließlich). Nenne ihre vollständigen Namen.`,
+		"43_join_city_region_table":       `Mitarbeiterverzeichnis:\n{{.DataBlock}}\n\nRegionstabelle:\n{{.RegionTable}}\n\nWelche Region hat die meisten Personen, wenn man die Stadt jeder Person über die Regionstabelle einer Region zuordnet? Nenne die Region und wie viele Personen sie hat.`,
+		"44_mixed_ages_with_absent":       `Mitgliederverzeichnis:\n{{.DataBlock}}\n\nNenne das Alter jeder der folgenden Personen, eine Zeile "Name: Alter" pro Person, in der angegebenen Reihenfolge. Einige von ihnen stehen möglicherweise nicht in der obigen Liste; antworte für diese mit "{{.AbsentAnswer}}" statt mit einem Alter.\n{{.QueryItemsFormatted}}`,
+		"45_mixed_script_cities":          `Mitarbeiterverzeichnis (einige Städtenamen sind in der Landessprache oder -schrift geschrieben):\n{{.DataBlock}}\n\nNenne die vollständigen Namen aller Personen mit der Berufsbezeichnung "{{.TargetJobTitle}}", die in {{.TargetCity}} wohnen, unabhängig davon, wie der Städtename geschrieben ist.`,
+		"46_years_until_milestone_age":    `Register:\n{{.DataBlock}}\n\nIn wie vielen Jahren wird {{.QueryName1}} {{.MilestoneAge}}? Antworte mit 0, wenn die Person bereits {{.MilestoneAge}} oder älter ist.`,
+		"47_unique_three_attribute_match": `Personalakten:\n{{.DataBlock}}\n\nGenau eine Person erfüllt alle drei folgenden Bedingungen:\n- Alter: {{.TargetAge}}\n- Stadt: {{.TargetCity}}\n- Berufsbezeichnung: {{.TargetJobTitle}}\n\nWie lautet der vollständige Name dieser Person?`,
+		"48_markdown_table_output":        `Mitarbeiterregister:\n{{.DataBlock}}\n\nFinde alle Personen mit der Berufsbezeichnung '{{.TargetJobTitle}}', die zwischen {{.MinAge}} und {{.MaxAge}} Jahre alt sind. Antworte nur mit einer Markdown-Tabelle mit genau diesen Spalten: Name | Age | City. Verwende eine Zeile pro Person.`,
+		"49_incremental_age_updates":      `Mitgliederdaten:\n{{.DataBlock}}\n\nAktualisierungen (der Reihe nach angewendet; eine spätere Aktualisierung ersetzt jeden früheren Wert):\n{{.UpdateLog}}\n\nWie alt ist jede dieser Personen nach Anwendung aller Aktualisierungen?\n{{.QueryItemsFormatted}}`,
+		"50_nested_json_by_city":          `Verzeichnis (JSON; jeder Schlüssel ist eine Stadt und listet die Personen auf, die dort wohnen):\n{{.DataBlock}}\n\nUnter welcher Stadt ist {{.QueryName1}} aufgeführt? Nenne die Stadt, das Alter und die Berufsbezeichnung.`,
+		"51_singleton_city":               `Einwohnerregister:\n{{.DataBlock}}\n\nWelche Stadt in der Liste hat genau einen Einwohner? Wenn es mehrere gibt, nenne eine davon. Gib die Stadt und den Namen ihres einzigen Einwohners an.`,
+		"52_filter_in_list_order":         `Mitarbeiterverzeichnis:\n{{.DataBlock}}\n\nNenne alle Personen mit der Berufsbezeichnung '{{.TargetJobTitle}}', die zwischen {{.MinAge}} und {{.MaxAge}} Jahre alt sind, in der Reihenfolge, in der sie in der obigen Liste stehen. Gib ihre vollständigen Namen an, einen pro Zeile.`,
+		"53_country_from_records":         `Mitgliederdatensätze:\n{{.DataBlock}}\n\nIn welcher Stadt und in welchem Land lebt {{.QueryName1}} laut diesen Datensätzen? Antworte anhand der Datensätze, auch wenn sie nicht mit deinem Wissen über die Stadt übereinstimmen.`,
+		"54_split_instruction_3":          `Lies die folgende Liste sorgfältig. Danach wirst du nach einigen der darin aufgeführten Personen gefragt und sollst nur anhand der Liste antworten.\n{{.DataBlock}}\n\nWie alt sind nun:\n{{.QueryItemsFormatted}}`,
+		"55_zero_count_job_city":          `Zensusdaten:\n{{.DataBlock}}\n\nWie viele Personen in der Liste haben die Berufsbezeichnung '{{.TargetJobTitle}}' UND leben in der Stadt '{{.TargetCity}}'? Nenne nur die Anzahl.`,
+		"56_job_count_difference":         `Mitarbeiterliste:\n{{.DataBlock}}\n\nWie viele Personen mehr haben die Berufsbezeichnung '{{.TargetJobTitle}}' als die Berufsbezeichnung '{{.TargetJobTitle2}}'? Nenne beide Anzahlen und dann die Anzahl '{{.TargetJobTitle}}' minus die Anzahl '{{.TargetJobTitle2}}' (eine negative Zahl, wenn es weniger sind).`,
+		"57_homonym_city_counts":          `Mitgliederdatensätze:\n{{.DataBlock}}\n\nIn diesen Datensätzen heißen zwei verschiedene Orte {{.TargetCity}}: einer in {{.TargetCountry}} und einer in {{.TargetCountry2}}. Wie viele Personen leben in {{.TargetCity}}, {{.TargetCountry}}, und wie viele in {{.TargetCity}}, {{.TargetCountry2}}? Nenne beide Anzahlen.`,
+	},
+	"fr": {
+		"01_standard_retrieval_10":        `Voici la liste :\n{{.DataBlock}}\n\nD'après la liste ci-dessus, quel est l'âge de :\n{{.QueryItemsFormatted}}`,
+		"02_different_phrasing_10":        `Voici les données suivantes :\n{{.DataBlock}}\n\nEn utilisant uniquement ces données, trouvez les âges associés à ces noms : {{.QueryItemsFormattedInline}}.`,
+		"03_fewer_items_5":                `Données :\n{{.DataBlock}}\n\nIndiquez l'âge de :\n{{.QueryItemsFormatted}}`,
+		"04_more_items_15":                `Liste :\n{{.DataBlock}}\n\nVeuillez indiquer l'âge des {{.QueryItemCount}} personnes suivantes :\n{{.QueryItemsFormatted}}`,
+		"05_start_end_focus_2":            `Jeu de données :\n{{.DataBlock}}\n\nQuel est l'âge de {{.QueryName1}} et l'âge de {{.QueryName2}} d'après ce jeu de données ?`,
+		"06_reverse_lookup_name":          `Noms et âges :\n{{.DataBlock}}\n\nD'après la liste, quelle personne a {{.QueryAge1}} ans ? Et qui a {{.QueryAge2}} ans ? (Si les âges ne sont pas uniques, indiquez tous les noms trouvés)`,
+		"07_combined_request":             `Données de référence :\n{{.DataBlock}}\n\nTrouvez l'âge de {{.QueryName1}}. Trouvez aussi l'âge de {{.QueryName2}}. Enfin, trouvez le nom associé à l'âge {{.QueryAge3}}.`,
+		"08_sequential_names_5":           `Journal de données :\n{{.DataBlock}}\n\nQuel est l'âge de {{.QueryName1}}, {{.QueryName2}}, {{.QueryName3}}, {{.QueryName4}} et {{.QueryName5}} ?`,
+		"09_widely_spaced_names_10":       `Liste des personnes :\n{{.DataBlock}}\n\nExtrayez l'âge de : {{.QueryItemsFormattedInline}}.`,
+		"10_retrieval_confirmation":       `Liste principale :\n{{.DataBlock}}\n\nIndiquez l'âge de {{.QueryItemsFormattedInline}}. Confirmez également si '{{.NonExistentName}}' figure dans cette liste.`,
+		"11_filter_city_get_name_job":     `Détail de la liste :\n{{.DataBlock}}\n\nIndiquez les noms et les intitulés de poste (Job Title) de toutes les personnes de la liste qui vivent dans la ville '{{.TargetCity}}'.`,
+		"12_filter_job_get_name_age":      `Données des employés :\n{{.DataBlock}}\n\nTrouvez les noms et les âges de toutes les personnes ayant l'intitulé de poste '{{.TargetJobTitle}}'.`,
+		"13_filter_age_city_get_name":     `Informations sur les résidents :\n{{.DataBlock}}\n\nQui dans la liste a entre {{.MinAge}} et {{.MaxAge}} ans ET vit à '{{.TargetCity}}' ? Indiquez leurs noms complets.`,
+		"14_count_job_city":               `Données du recensement :\n{{.DataBlock}}\n\nCombien de personnes de la liste ont l'intitulé de poste '{{.TargetJobTitle}}' ET vivent dans la ville '{{.TargetCity}}' ? Indiquez uniquement le nombre.`,
+		"15_filter_job_retrieve_all":      `Dossiers du personnel :\n{{.DataBlock}}\n\nFournissez toutes les informations disponibles (Name, Age, City, Job Title) pour toutes les personnes dont l'intitulé de poste est '{{.TargetJobTitle}}'.`,
+		"16_sum_ages_3":                   `Liste des membres :\n{{.DataBlock}}\n\nQuelle est la somme des âges de {{.QueryName1}}, {{.QueryName2}} et {{.QueryName3}} ? Indiquez le total.`,
+		"17_age_difference_2":             `Registre :\n{{.DataBlock}}\n\nDe combien d'années {{.QueryName1}} est-il plus âgé que {{.QueryName2}} ?`,
+		"18_unknown_city":                 `Fiches de contact (une City égale à '-' signifie inconnue) :\n{{.DataBlock}}\n\nIndiquez les noms complets de toutes les personnes dont la ville est inconnue.`,
+		"19_sorted_ages_10":               `Effectif :\n{{.DataBlock}}\n\nIndiquez l'âge des {{.QueryItemCount}} personnes suivantes, triées de la plus jeune à la plus âgée :\n{{.QueryItemsFormatted}}`,
+		"20_filter_job_substring":         `Annuaire du personnel :\n{{.DataBlock}}\n\nIndiquez les noms et les intitulés de poste de toutes les personnes dont l'intitulé de poste contient le mot '{{.TargetKeyword}}'.`,
+		"21_absent_field_favorite_color":  `Profils des membres :\n{{.DataBlock}}\n\nQuelle est la couleur préférée de {{.QueryName1}} ?`,
+		"22_presence_check_mixed":         `Annuaire des membres :\n{{.DataBlock}}\n\nPour chacun des noms suivants, indiquez si la personne est présente ou absente de la liste ci-dessus :\n{{.QueryItemsFormatted}}`,
+		"23_composite_key_job_city":       `Registre communal :\n{{.DataBlock}}\n\n{{if .Unique}}Quels sont le nom et l'âge de la personne ayant l'intitulé de poste '{{.TargetJobTitle}}' qui vit à {{.TargetCity}} ?{{else}}Indiquez les noms et les âges de toutes les personnes ayant l'intitulé de poste '{{.TargetJobTitle}}' qui vivent à {{.TargetCity}}.{{end}}`,
+		"24_varying_delimiters_10":        `Enregistrements fusionnés (les lignes proviennent de sources différentes) :\n{{.DataBlock}}\n\nQuel est l'âge des personnes suivantes ?\n{{.QueryItemsFormatted}}`,
+		"25_json_output_ages_10":          `Fiches des membres :\n{{.DataBlock}}\n\nTrouvez l'âge des personnes suivantes :\n{{.QueryItemsFormatted}}\n\nRépondez uniquement avec un objet JSON de la forme {"answers": [{"name": "<nom complet>", "age": <entier>}]}, avec un élément par personne.`,
+		"26_city_job_breakdown":           `Données du recensement :\n{{.DataBlock}}\n\nParmi les personnes qui vivent à {{.TargetCity}}, combien occupent chaque intitulé de poste ? Fournissez une répartition indiquant chaque intitulé et son effectif.`,
+		"27_duplicate_detection":          `Liste des participants :\n{{.DataBlock}}\n\nY a-t-il des personnes listées plus d'une fois ? Si oui, lesquelles ? Indiquez le nom complet de chaque personne qui apparaît plus d'une fois, ou répondez 'none'.`,
+		"28_two_section_scoped":           `Registres de l'entreprise (deux listes distinctes) :\n{{.DataBlock}}\n\nCertains noms figurent dans les deux listes avec des informations différentes. En utilisant uniquement la liste {{.TargetSection}}, quel est l'âge des personnes suivantes ?\n{{.QueryItemsFormatted}}`,
+		"29_split_attribute_join_5":       `Fiches des membres (un âge '-' est donné dans une ligne 'Age index' séparée ailleurs dans la liste) :\n{{.DataBlock}}\n\nQuel est l'âge des personnes suivantes ?\n{{.QueryItemsFormatted}}`,
+		"30_age_threshold_comparison":     `Données du recensement :\n{{.DataBlock}}\n\nY a-t-il dans la liste plus de personnes de plus de {{.OverAge}} ans que de moins de {{.UnderAge}} ans ? Donnez les deux effectifs, puis répondez oui ou non.`,
+		"31_corrupted_entry_lookup":       `Fiches des membres (reproduisez les valeurs exactement telles qu'elles figurent) :\n{{.DataBlock}}\n\nQuels sont l'âge et la ville de chacune des personnes suivantes, exactement comme indiqué dans la liste ?\n{{.QueryItemsFormatted}}`,
+		"32_most_common_city":             `Données du recensement :\n{{.DataBlock}}\n\nQuelle ville apparaît le plus souvent dans la liste ? Indiquez la ville et le nombre de personnes qui y vivent.`,
+		"33_most_common_job":              `Données du recensement :\n{{.DataBlock}}\n\nQuel est l'intitulé de poste le plus courant dans la liste ? Indiquez l'intitulé et le nombre de personnes qui l'occupent.`,
+		"34_born_in_year":                 `Données du recensement :\n{{.DataBlock}}\n\nQui est né en {{.TargetBirthYear}} ? Indiquez le nom complet de toutes les personnes nées cette année-là.`,
+		"35_hard_reverse_lookup_all":      `Noms et âges :\n{{.DataBlock}}\n\nListez toutes les personnes de la liste qui ont exactement {{.QueryAge1}} ans. Plusieurs d'entre elles ont le même intitulé de poste ou le même nom de famille, vérifiez donc chaque ligne ; incluez-les toutes et indiquez combien il y en a.`,
+		"36_line_number_lookup":           `Enregistrements numérotés :\n{{.DataBlock}}\n\nQue contient la ligne {{.LineNumber}} ? Indiquez le nom, l'âge, la ville et l'intitulé de poste qui y figurent.`,
+		"37_prompt_injection_decoy_5":     `Fiches des membres :\n{{.DataBlock}}\n\nQuel est l'âge des personnes suivantes d'après les fiches ?\n{{.QueryItemsFormatted}}`,
+		"38_indirect_reference_city":      `Annuaire du personnel :\n{{.DataBlock}}\n\nPensez à la personne la plus {{if .Oldest}}âgée{{else}}jeune{{end}} ayant l'intitulé {{.TargetJobTitle}} dans la liste. Dans quelle ville vit-elle ? Indiquez son nom et sa ville.`,
+		"39_many_needles":                 `Fiches des membres :\n{{.DataBlock}}\n\nDonnez l'âge de chacune de ces {{.QueryItemCount}} personnes, une ligne « Nom : âge » par personne : {{.QueryItemsFormattedInline}}`,
+		"40_distinct_cities_for_job":      `Données du recensement :\n{{.DataBlock}}\n\nParmi les personnes ayant l'intitulé de poste '{{.TargetJobTitle}}', dans combien de villes distinctes vivent-elles ? Donnez le nombre et listez les villes.`,
+		"41_top_5_oldest_with_ties":       `Annuaire du personnel :\n{{.DataBlock}}\n\nParmi les personnes ayant l'intitulé de poste '{{.TargetJobTitle}}', listez les {{.TopN}} plus âgées, de la plus âgée à la plus jeune, avec l'âge de chacune. Si plusieurs personnes sont à égalité avec la {{.TopN}}e plus âgée, incluez-les toutes, même si la liste dépasse alors {{.TopN}} personnes.`,
+		"42_qualitative_age_band":         `Annuaire du personnel :\n{{.DataBlock}}\n\nListez toutes les personnes ayant l'intitulé de poste '{{.TargetJobTitle}}' qui sont {{index .AgeDescriptor "fr"}} (entre {{.MinAge}} et {{.MaxAge}} ans inclus). Donnez leurs noms complets.`,
+		"43_join_city_region_table":       `Annuaire du personnel :\n{{.DataBlock}}\n\nTableau des régions :\n{{.RegionTable}}\n\nEn utilisant le tableau des régions pour trouver la région de la ville de chaque personne, quelle région compte le plus de personnes ? Donnez la région et son nombre de personnes.`,
+		"44_mixed_ages_with_absent":       `Annuaire des membres :\n{{.DataBlock}}\n\nDonnez l'âge de chacune des personnes suivantes, une ligne « Nom : âge » par personne, dans l'ordre indiqué. Certaines ne figurent peut-être pas dans la liste ci-dessus ; pour celles-ci, répondez « {{.AbsentAnswer}} » au lieu d'un âge.\n{{.QueryItemsFormatted}}`,
+		"45_mixed_script_cities":          `Annuaire du personnel (certains noms de villes sont écrits dans la langue ou l'écriture locale) :\n{{.DataBlock}}\n\nDonnez les noms complets de toutes les personnes ayant le poste « {{.TargetJobTitle}} » qui habitent à {{.TargetCity}}, quelle que soit la graphie du nom de la ville.`,
+		"46_years_until_milestone_age":    `Registre :\n{{.DataBlock}}\n\nDans combien d'années {{.QueryName1}} aura-t-il {{.MilestoneAge}} ans ? Répondez 0 s'il a déjà {{.MilestoneAge}} ans ou plus.`,
+		"47_unique_three_attribute_match": `Dossiers du personnel :\n{{.DataBlock}}\n\nUne seule personne remplit ces trois conditions :\n- Âge : {{.TargetAge}}\n- Ville : {{.TargetCity}}\n- Poste : {{.TargetJobTitle}}\n\nQuel est le nom complet de cette personne ?`,
+		"48_markdown_table_output":        `Registre des employés :\n{{.DataBlock}}\n\nTrouvez toutes les personnes ayant le poste '{{.TargetJobTitle}}' qui ont entre {{.MinAge}} et {{.MaxAge}} ans. Répondez uniquement par un tableau Markdown avec exactement ces colonnes : Name | Age | City. Utilisez une ligne par personne.`,
+		"49_incremental_age_updates":      `Fiches des membres :\n{{.DataBlock}}\n\nMises à jour (appliquées dans l'ordre ; une mise à jour ultérieure remplace toute valeur antérieure) :\n{{.UpdateLog}}\n\nAprès application de toutes les mises à jour, quel est l'âge actuel de chacune de ces personnes ?\n{{.QueryItemsFormatted}}`,
+		"50_nested_json_by_city":          `Annuaire (JSON ; chaque clé est une ville et liste les personnes qui y habitent) :\n{{.DataBlock}}\n\nSous quelle ville figure {{.QueryName1}} ? Indique la ville, son âge et son intitulé de poste.`,
+		"51_singleton_city":               `Registre des habitants :\n{{.DataBlock}}\n\nQuelle ville de la liste compte exactement un habitant ? S'il y en a plusieurs, nomme l'une d'elles. Indique la ville et le nom de son unique habitant.`,
+		"52_filter_in_list_order":         `Annuaire du personnel :\n{{.DataBlock}}\n\nCite toutes les personnes ayant l'intitulé de poste '{{.TargetJobTitle}}' qui ont entre {{.MinAge}} et {{.MaxAge}} ans, dans l'ordre où elles apparaissent dans la liste ci-dessus. Donne leurs noms complets, un par ligne.`,
+		"53_country_from_records":         `Registres des membres :\n{{.DataBlock}}\n\nSelon ces registres, dans quelle ville et quel pays vit {{.QueryName1}} ? Réponds d'après les registres, même s'ils contredisent ce que tu sais de la ville.`,
+		"54_split_instruction_3":          `Lis attentivement la liste suivante. On t'interrogera ensuite sur certaines des personnes qui y figurent, et tu devras répondre uniquement à partir de la liste.\n{{.DataBlock}}\n\nMaintenant, quel âge ont :\n{{.QueryItemsFormatted}}`,
+		"55_zero_count_job_city":          `Données du recensement :\n{{.DataBlock}}\n\nCombien de personnes de la liste ont l'intitulé de poste '{{.TargetJobTitle}}' ET vivent dans la ville '{{.TargetCity}}' ? Indiquez uniquement le nombre.`,
+		"56_job_count_difference":         `Liste du personnel :\n{{.DataBlock}}\n\nCombien de personnes de plus ont l'intitulé de poste '{{.TargetJobTitle}}' que l'intitulé de poste '{{.TargetJobTitle2}}' ? Donne les deux nombres, puis le nombre de '{{.TargetJobTitle}}' moins le nombre de '{{.TargetJobTitle2}}' (un nombre négatif s'il y en a moins).`,
+		"57_homonym_city_counts":          `Registres des membres :\n{{.DataBlock}}\n\nDans ces registres, deux lieux différents s'appellent {{.TargetCity}} : l'un en {{.TargetCountry}} et l'autre en {{.TargetCountry2}}. Combien de personnes vivent à {{.TargetCity}}, {{.TargetCountry}}, et combien vivent à {{.TargetCity}}, {{.TargetCountry2}} ? Donne les deux nombres.`,
+	},
+}
+
+// --- Answer-Format Instructions ---
+// outputInstructions maps a language code ("en" for English) and a PromptConfig.OutputInstruction
+// kind to the sentence -strict-format appends to the question.
+var outputInstructions = map[string]map[string]string{
+	"en": {
+		OUTPUT_COUNT: "Reply with a single integer and nothing else.",
+		OUTPUT_NAMES: "Reply with a bulleted list of full names, one per line, and nothing else.",
+		OUTPUT_AGES:  "Reply with one line per person in the form 'Name: age', and nothing else.",
+	},
+	"es": {
+		OUTPUT_COUNT: "Responde solo con un único número entero.",
+		OUTPUT_NAMES: "Responde solo con una lista con viñetas de nombres completos, uno por línea.",
+		OUTPUT_AGES:  "Responde con una línea por persona con el formato 'Nombre: edad', y nada más.",
+	},
+	"de": {
+		OUTPUT_COUNT: "Antworte nur mit einer einzigen ganzen Zahl.",
+		OUTPUT_NAMES: "Antworte nur mit einer Aufzählung der vollständigen Namen, einer pro Zeile.",
+		OUTPUT_AGES:  "Antworte mit einer Zeile pro Person im Format 'Name: Alter' und sonst nichts.",
+	},
+	"fr": {
+		OUTPUT_COUNT: "Réponds uniquement par un seul nombre entier.",
+		OUTPUT_NAMES: "Réponds uniquement par une liste à puces des noms complets, un par ligne.",
+		OUTPUT_AGES:  "Réponds avec une ligne par personne au format 'Nom : âge', et rien d'autre.",
+	},
+}
@@ -0,0 +1,199 @@
+package promptgen
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// readOutputFile reads path, or path+".gz" (decompressed) when only the compressed file exists.
+func readOutputFile(path string) ([]byte, error) {
+	if content, err := os.ReadFile(path); err == nil {
+		return content, nil
+	}
+	file, err := os.Open(path + ".gz")
+	if err != nil {
+		return nil, fmt.Errorf("no %s or %s.gz", path, path)
+	}
+	defer file.Close()
+	zr, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("decompressing %s.gz: %w", path, err)
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
+
+// loadMasterData reads dir's master data. Every entry must give its name and age: JSON decodes a
+// missing or null number as 0, which would pass for a real age.
+func loadMasterData(dir string) ([]PersonEntry, error) {
+	content, err := readOutputFile(filepath.Join(dir, MASTER_DATA_FILENAME))
+	if err != nil {
+		return nil, err
+	}
+	var rows []map[string]json.RawMessage
+	if err := json.Unmarshal(content, &rows); err != nil {
+		return nil, fmt.Errorf("decoding master data: %w", err)
+	}
+	for i, row := range rows {
+		for _, field := range []string{"name", "age"} {
+			if raw, ok := row[field]; !ok {
+				return nil, fmt.Errorf("master data entry %d has no %s", i+1, field)
+			} else if isJSONNull(raw) {
+				return nil, fmt.Errorf("master data entry %d has a null %s", i+1, field)
+			}
+		}
+	}
+	var data []PersonEntry
+	if err := json.Unmarshal(content, &data); err != nil {
+		return nil, fmt.Errorf("decoding master data: %w", err)
+	}
+	return data, nil
+}
+
+// --- Function to Validate an Existing Prompt Directory ---
+// validatePromptDir re-checks every answer in dir's key against dir's master data and prompt files
+// without regenerating anything. It returns one message per inconsistency.
+func validatePromptDir(dir string) ([]string, error) {
+	data, err := loadMasterData(dir)
+	if err != nil {
+		return nil, err
+	}
+	entryByName := make(map[string]PersonEntry, len(data))
+	for _, entry := range data {
+		entryByName[entry.Name] = entry
+	}
+	answers, err := loadAnswerKey(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	problems := []string{}
+	for _, answer := range answers {
+		prompt, err := readOutputFile(filepath.Join(dir, strings.TrimSuffix(answer.File, ".gz")))
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", answer.File, err))
+			continue
+		}
+		// An -annotate comment names every expected person, so it must not count as the prompt mentioning them.
+		for _, problem := range validateAnswer(answer, entryByName, stripAnswerAnnotation(string(prompt))) {
+			problems = append(problems, fmt.Sprintf("%s: %s", answer.File, problem))
+		}
+	}
+	return problems, nil
+}
+
+// ValidateDir runs validatePromptDir on opts.ValidateOnly, logging each problem it finds. It
+// returns an error if the directory cannot be read or any answer disagrees with the data.
+func ValidateDir(opts Options) error {
+	dir, out := opts.ValidateOnly, opts.out()
+	problems, err := validatePromptDir(dir)
+	if err != nil {
+		return fmt.Errorf("validating %s: %w", dir, err)
+	}
+	if len(problems) > 0 {
+		for _, problem := range problems {
+			log.Printf("Inconsistent: %s", problem)
+		}
+		return fmt.Errorf("validation found %d problem(s) in %s", len(problems), dir)
+	}
+	fmt.Fprintf(out, "Validation passed: every checked answer in %s matches its data.\n", dir)
+	return nil
+}
+
+// validateAnswer checks the parts of an answer that can be recomputed from the data alone:
+// name->age maps (top level or under "ages") and lists of person records. Other fields, such as
+// counts over the whole list, are covered by re-checking the people they name.
+func validateAnswer(answer PromptAnswer, entryByName map[string]PersonEntry, prompt string) []string {
+	problems := []string{}
+	if strings.HasPrefix(answer.Desc, BUNDLE_DESC_PREFIX) {
+		// -questions-per-file bundles key one answer per question number; check each on its own.
+		questions, _ := answer.Expected.(map[string]interface{})
+		labels := make([]string, 0, len(questions))
+		for label := range questions {
+			labels = append(labels, label)
+		}
+		sort.Strings(labels)
+		for _, label := range labels {
+			fields, _ := questions[label].(map[string]interface{})
+			desc, _ := fields["desc"].(string)
+			for _, problem := range validateAnswer(PromptAnswer{Desc: desc, Expected: fields["expected"]}, entryByName, prompt) {
+				problems = append(problems, fmt.Sprintf("%s (%s): %s", label, desc, problem))
+			}
+		}
+		return problems
+	}
+	checkAges := func(ages map[string]int) {
+		names := make([]string, 0, len(ages))
+		for name := range ages {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			entry, ok := entryByName[name]
+			if !ok {
+				problems = append(problems, fmt.Sprintf("%s is not in the master data", name))
+			} else if entry.Age != ages[name] {
+				problems = append(problems, fmt.Sprintf("%s: key says age %d, data says %d", name, ages[name], entry.Age))
+			}
+			if !strings.Contains(prompt, name) {
+				problems = append(problems, fmt.Sprintf("%s does not appear in the prompt", name))
+			}
+		}
+	}
+	checkEntries := func(raw interface{}) {
+		items, ok := raw.([]interface{})
+		if !ok {
+			return
+		}
+		for _, item := range items {
+			fields, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			name, _ := fields["name"].(string)
+			rawAge, hasAge := fields["age"]
+			if name == "" || !hasAge {
+				continue
+			}
+			age, ok := wholeNumber(rawAge)
+			if !ok {
+				problems = append(problems, fmt.Sprintf("%s: key record has age %v (%T), not a whole number", name, rawAge, rawAge))
+				continue
+			}
+			entry, found := entryByName[name]
+			if !found {
+				problems = append(problems, fmt.Sprintf("%s is not in the master data", name))
+				continue
+			}
+			// Only the fields the record carries are compared (NameAge records have no city or job).
+			city, hasCity := fields["city"].(string)
+			jobTitle, hasJob := fields["job_title"].(string)
+			if entry.Age != age || (hasCity && entry.City != city) || (hasJob && entry.JobTitle != jobTitle) {
+				problems = append(problems, fmt.Sprintf("%s: key record differs from the data", name))
+			}
+		}
+	}
+
+	if ages, ok := expectedAges(answer.Expected); ok {
+		checkAges(ages)
+		return problems
+	}
+	checkEntries(answer.Expected)
+	if fields, ok := answer.Expected.(map[string]interface{}); ok {
+		if _, ok := fields["section"]; ok {
+			return nil // Two-section prompts draw their second section per prompt; it is not in the master data
+		}
+		if ages, ok := expectedAges(fields["ages"]); ok {
+			checkAges(ages)
+		}
+		checkEntries(fields["matches"])
+	}
+	return problems
+}
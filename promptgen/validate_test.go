@@ -0,0 +1,72 @@
+package promptgen
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateAnswerRecordAges(t *testing.T) {
+	entryByName := map[string]PersonEntry{"Queen Weber": testPeople(1)[0]}
+	tests := []struct {
+		name    string
+		age     interface{}
+		wantErr string
+	}{
+		{name: "JSON float64", age: float64(49)},
+		{name: "YAML int", age: 49},
+		{name: "YAML int64", age: int64(49)},
+		{name: "wrong age", age: 50, wantErr: "differs from the data"},
+		{name: "fractional age", age: 49.5, wantErr: "not a whole number"},
+		{name: "string age", age: "49", wantErr: "not a whole number"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			answer := PromptAnswer{Desc: "11_filter_city_get_name_job", Expected: []interface{}{
+				map[string]interface{}{"name": "Queen Weber", "age": tt.age, "city": "Tartu"},
+			}}
+			problems := validateAnswer(answer, entryByName, "Queen Weber")
+			if tt.wantErr == "" {
+				if len(problems) > 0 {
+					t.Errorf("unexpected problems: %v", problems)
+				}
+				return
+			}
+			if len(problems) != 1 || !strings.Contains(problems[0], tt.wantErr) {
+				t.Errorf("problems = %v, want one containing %q", problems, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadMasterDataRejectsNullAndMissingAges(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "valid", content: `[{"name": "Queen Weber", "age": 49, "city": "Tartu", "job_title": "Scientist"}]`},
+		{name: "null age", content: `[{"name": "Queen Weber", "age": null, "city": "Tartu", "job_title": "Scientist"}]`, wantErr: "null age"},
+		{name: "missing age", content: `[{"name": "Queen Weber", "city": "Tartu", "job_title": "Scientist"}]`, wantErr: "no age"},
+		{name: "missing name", content: `[{"age": 49, "city": "Tartu", "job_title": "Scientist"}]`, wantErr: "no name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, MASTER_DATA_FILENAME), []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			data, err := loadMasterData(dir)
+			if tt.wantErr == "" {
+				if err != nil || len(data) != 1 || data[0].Age != 49 {
+					t.Errorf("loadMasterData = %v, %v; want one entry aged 49", data, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}